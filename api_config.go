@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/redis"
 )
 
@@ -12,6 +13,6 @@ type APIConfig struct {
 	DB       *database.Queries
 	RDB      *redis.RedisClient
 	Handlers *handlers.Handlers
-	JwtSecret string
+	JWTKeys   *jwtkeys.KeySet
 	S3        *s3.Client
 }
@@ -0,0 +1,81 @@
+// Command reconcile repairs each server's stored member and boost counts
+// from its membership and boost rows. Run it once after upgrading to the
+// member count trigger, or whenever counts look wrong:
+//
+//	go run ./cmd/reconcile [-dry-run]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/levels"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report drifted servers without repairing them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file found or error loading it: %v", err)
+	}
+
+	db, err := sql.Open("postgres", os.Getenv("DB"))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Holding off joins, leaves and boosts while recounting keeps a change
+	// that lands mid-scan from being overwritten with a stale count. Reads
+	// carry on as normal.
+	_, err = tx.ExecContext(ctx, "LOCK TABLE user_servers, server_boosts IN SHARE MODE")
+	if err != nil {
+		log.Fatalf("Failed to lock membership tables: %v", err)
+	}
+
+	repaired, err := database.New(db).WithTx(tx).ReconcileServerCounts(ctx, database.ReconcileServerCountsParams{
+		LevelThresholds: levels.Thresholds(),
+		LevelMaxMembers: levels.MaxMembers(),
+	})
+	if err != nil {
+		log.Fatalf("Failed to reconcile server counts: %v", err)
+	}
+
+	for _, server := range repaired {
+		log.Printf("%s (%s): members %d -> %d, boosts %d -> %d",
+			server.ServerName, server.ID,
+			server.StoredMembers.Int32, server.Members,
+			server.StoredBoosts, server.Boosts,
+		)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: %d servers would be repaired", len(repaired))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Failed to commit repairs: %v", err)
+	}
+
+	log.Printf("Repaired %d servers", len(repaired))
+}
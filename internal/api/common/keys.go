@@ -10,4 +10,8 @@ type ErrorResponse struct {
 
 type ContextKey string
 
-const UserContextKey ContextKey = "user"
\ No newline at end of file
+const UserContextKey ContextKey = "user"
+
+const SessionContextKey ContextKey = "session"
+
+const SessionCachePrefix = "session"
@@ -0,0 +1,36 @@
+package common
+
+// Scopes a personal access token can be granted. Requests authenticated with
+// a login session are not limited by scopes.
+const (
+	ScopeServersRead   = "servers:read"
+	ScopeServersWrite  = "servers:write"
+	ScopeChannelsRead  = "channels:read"
+	ScopeChannelsWrite = "channels:write"
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+)
+
+const ScopesContextKey ContextKey = "scopes"
+
+var Scopes = []string{
+	ScopeServersRead,
+	ScopeServersWrite,
+	ScopeChannelsRead,
+	ScopeChannelsWrite,
+	ScopeMessagesRead,
+	ScopeMessagesWrite,
+}
+
+func IsValidScope(scope string) bool {
+	return HasScope(Scopes, scope)
+}
+
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebSocketTicketPrefix = "wsticket"
+	WebSocketTicketTTL    = 30 * time.Second
+)
+
+type WebSocketTicket struct {
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id"`
+}
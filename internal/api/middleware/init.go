@@ -2,16 +2,17 @@ package middleware
 
 import (
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/redis"
 )
 
 type Middleware struct {
 	DB  *database.Queries
 	RDB *redis.RedisClient
-	JWT string
+	JWT *jwtkeys.KeySet
 }
 
-func NewMiddleware(db *database.Queries, rdb *redis.RedisClient, jwt string) *Middleware {
+func NewMiddleware(db *database.Queries, rdb *redis.RedisClient, jwt *jwtkeys.KeySet) *Middleware {
 	return &Middleware{
 		DB:  db,
 		RDB: rdb,
@@ -8,94 +8,188 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
 	"github.com/jimmyvallejo/gleamspeak-api/utils"
 )
 
+// IsAuthenticated accepts a session access token from the access_token cookie
+// or an Authorization: Bearer header. Personal access tokens are rejected;
+// routes open to them are wrapped with IsAuthenticatedScoped instead.
 func (m *Middleware) IsAuthenticated(next http.HandlerFunc) http.HandlerFunc {
+	return m.authenticate("", next)
+}
+
+// IsAuthenticatedScoped behaves like IsAuthenticated but also accepts personal
+// access tokens that were granted scope.
+func (m *Middleware) IsAuthenticatedScoped(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return m.authenticate(scope, next)
+}
+
+func (m *Middleware) authenticate(scope string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("access_token")
+		token, err := requestToken(r)
 		if err != nil {
-			if err == http.ErrNoCookie {
+			var tokenErr *utils.TokenError
+			switch {
+			case errors.Is(err, http.ErrNoCookie):
 				http.Error(w, "Access token not found", http.StatusUnauthorized)
-			} else {
+			case errors.As(err, &tokenErr):
+				http.Error(w, tokenErr.Message, tokenErr.Code)
+			default:
 				http.Error(w, "Error reading cookie", http.StatusBadRequest)
 			}
 			return
 		}
 
-		validated, err := utils.ValidateToken(cookie.Value, m.JWT)
+		if utils.IsPersonalAccessToken(token) {
+			m.serveWithPersonalAccessToken(w, r, token, scope, next)
+			return
+		}
+
+		validated, err := utils.ValidateToken(token, m.JWT)
 		if err != nil {
 			http.Error(w, "error validating token", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := validated.Claims.(*jwt.RegisteredClaims)
+		claims, ok := validated.Claims.(*utils.Claims)
 		if !ok {
 			http.Error(w, "error getting claims from token", http.StatusInternalServerError)
 			return
 		}
 
+		// Refresh tokens share the session of the access tokens they mint
+		// and must only ever be exchanged at the refresh endpoint.
+		if claims.TokenType != utils.TokenTypeAccess || claims.ID != "" {
+			http.Error(w, "invalid token type", http.StatusUnauthorized)
+			return
+		}
+
 		idStr, err := claims.GetSubject()
 		if err != nil {
 			http.Error(w, "error getting subject from claims", http.StatusInternalServerError)
 			return
 		}
 
-		var user database.User
-		err = m.RDB.GetJSON("user"+idStr, &user)
-		if err == nil && user.ID != uuid.Nil {
-			log.Printf("Cache hit: User retrieved from cache: ID=%s, Email=%s, Handle=%s",
-				user.ID, user.Email, user.Handle)
-
-			ctx := context.WithValue(r.Context(), common.UserContextKey, user)
-			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			http.Error(w, "error getting session from claims", http.StatusUnauthorized)
 			return
 		}
 
+		active, err := m.isSessionActive(r.Context(), sessionID, idStr)
 		if err != nil {
-			log.Printf("Cache miss: Error retrieving user from cache: %v", err)
-		} else {
-			log.Printf("Cache miss: User not found in cache or invalid")
+			log.Printf("Error checking session: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+		if !active {
+			http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		m.serveWithUser(w, r, idStr, sessionID, next)
+	}
+}
 
-		log.Printf("Fetching user from database: ID=%s", idStr)
+// requestToken prefers an explicit Authorization header over the cookie.
+func requestToken(r *http.Request) (string, error) {
+	if r.Header.Get("Authorization") != "" {
+		return utils.ExtractToken(r, "Bearer ")
+	}
 
-		id, err := uuid.Parse(idStr)
-		if err != nil {
+	cookie, err := r.Cookie("access_token")
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+func (m *Middleware) serveWithUser(w http.ResponseWriter, r *http.Request, idStr string, sessionID uuid.UUID, next http.HandlerFunc) {
+	user, err := m.loadUser(r.Context(), idStr)
+	if err != nil {
+		switch {
+		case errors.Is(err, errInvalidUserID):
 			http.Error(w, "invalid user ID format", http.StatusInternalServerError)
-			return
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			log.Printf("Error getting user by ID: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), common.UserContextKey, user)
+	ctx = context.WithValue(ctx, common.SessionContextKey, sessionID)
+	r = r.WithContext(ctx)
+
+	next.ServeHTTP(w, r)
+}
 
-		u, err := m.DB.GetUserByID(r.Context(), id)
+// isSessionActive reports whether the login session an access token was
+// issued for still exists, belongs to the token's subject and is not revoked.
+func (m *Middleware) isSessionActive(ctx context.Context, sessionID uuid.UUID, idStr string) (bool, error) {
+	var session database.Session
+	err := m.RDB.GetJSON(common.SessionCachePrefix+sessionID.String(), &session)
+	if err != nil || session.ID == uuid.Nil {
+		session, err = m.DB.GetSessionByID(ctx, sessionID)
 		if err != nil {
-			switch {
-			case errors.Is(err, sql.ErrNoRows):
-				http.Error(w, "User not found", http.StatusNotFound)
-			default:
-				log.Printf("Error getting user by ID: %v", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, nil
 			}
-			return
+			return false, err
 		}
 
-		log.Printf("User fetched from database: ID=%s, Email=%s, Handle=%s",
-			u.ID, u.Email, u.Handle)
-
-		err = m.RDB.SetJson("user"+idStr, u, time.Hour)
+		err = m.RDB.SetJson(common.SessionCachePrefix+sessionID.String(), session, time.Hour)
 		if err != nil {
-			log.Printf("Failed to save user to cache: %v", err)
-		} else {
-			log.Printf("User saved to cache: ID=%s", u.ID)
+			log.Printf("Failed to save session to cache: %v", err)
 		}
+	}
+
+	return session.UserID.String() == idStr && !session.RevokedAt.Valid, nil
+}
+
+var errInvalidUserID = errors.New("invalid user ID format")
+
+func (m *Middleware) loadUser(ctx context.Context, idStr string) (database.User, error) {
+	var user database.User
+	err := m.RDB.GetJSON("user"+idStr, &user)
+	if err == nil && user.ID != uuid.Nil {
+		log.Printf("Cache hit: User retrieved from cache: ID=%s, Email=%s, Handle=%s",
+			user.ID, user.Email, user.Handle)
+		return user, nil
+	}
+
+	if err != nil {
+		log.Printf("Cache miss: Error retrieving user from cache: %v", err)
+	} else {
+		log.Printf("Cache miss: User not found in cache or invalid")
+	}
 
-		ctx := context.WithValue(r.Context(), common.UserContextKey, u)
-		r = r.WithContext(ctx)
+	log.Printf("Fetching user from database: ID=%s", idStr)
 
-		next.ServeHTTP(w, r)
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return database.User{}, errInvalidUserID
 	}
+
+	u, err := m.DB.GetUserByID(ctx, id)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	log.Printf("User fetched from database: ID=%s, Email=%s, Handle=%s",
+		u.ID, u.Email, u.Handle)
+
+	err = m.RDB.SetJson("user"+idStr, u, time.Hour)
+	if err != nil {
+		log.Printf("Failed to save user to cache: %v", err)
+	} else {
+		log.Printf("User saved to cache: ID=%s", u.ID)
+	}
+
+	return u, nil
 }
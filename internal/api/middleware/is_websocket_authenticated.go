@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+)
+
+// IsWebSocketAuthenticated authenticates a websocket upgrade. Browsers send the
+// access_token cookie; clients that cannot send cookies pass a single-use
+// ticket from POST /v1/ws/ticket in the ticket query parameter instead.
+// Scripts may also connect with a personal access token granted messages:read.
+func (m *Middleware) IsWebSocketAuthenticated(next http.HandlerFunc) http.HandlerFunc {
+	tokenAuth := m.IsAuthenticatedScoped(common.ScopeMessagesRead, next)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			tokenAuth(w, r)
+			return
+		}
+
+		var redeemed common.WebSocketTicket
+		err := m.RDB.GetDelJSON(common.WebSocketTicketPrefix+ticket, &redeemed)
+		if err != nil {
+			log.Printf("Failed to redeem websocket ticket: %v", err)
+			http.Error(w, "Invalid or expired ticket", http.StatusUnauthorized)
+			return
+		}
+
+		// The session may have been signed out since the ticket was issued.
+		active, err := m.isSessionActive(r.Context(), redeemed.SessionID, redeemed.UserID.String())
+		if err != nil {
+			log.Printf("Error checking session: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Session has been revoked", http.StatusUnauthorized)
+			return
+		}
+
+		m.serveWithUser(w, r, redeemed.UserID.String(), redeemed.SessionID, next)
+	}
+}
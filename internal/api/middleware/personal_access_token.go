@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+// lastUsedInterval limits how often last_used_at is written for busy tokens.
+const lastUsedInterval = time.Minute
+
+func (m *Middleware) serveWithPersonalAccessToken(w http.ResponseWriter, r *http.Request, token, scope string, next http.HandlerFunc) {
+	if scope == "" {
+		http.Error(w, "Personal access tokens cannot be used for this route", http.StatusForbidden)
+		return
+	}
+
+	pat, err := m.DB.GetPersonalAccessTokenByHash(r.Context(), utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Invalid access token", http.StatusUnauthorized)
+		} else {
+			log.Printf("Error getting personal access token: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+
+	if pat.ExpiresAt.Valid && now.After(pat.ExpiresAt.Time) {
+		http.Error(w, "Access token is expired", http.StatusUnauthorized)
+		return
+	}
+
+	if !common.HasScope(pat.Scopes, scope) {
+		http.Error(w, "Access token is missing scope "+scope, http.StatusForbidden)
+		return
+	}
+
+	if !pat.LastUsedAt.Valid || now.Sub(pat.LastUsedAt.Time) > lastUsedInterval {
+		err = m.DB.TouchPersonalAccessToken(r.Context(), database.TouchPersonalAccessTokenParams{
+			ID: pat.ID,
+			LastUsedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to update personal access token last used time: %v", err)
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), common.ScopesContextKey, pat.Scopes)
+	m.serveWithUser(w, r.WithContext(ctx), pat.UserID.String(), uuid.Nil, next)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+// RequireVerified rejects users who have not confirmed their email address.
+// It must run after IsAuthenticated so the user is already in the context.
+func (m *Middleware) RequireVerified(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(common.UserContextKey).(database.User)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !user.IsVerified.Bool {
+			http.Error(w, "Email verification required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
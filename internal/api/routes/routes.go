@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/middleware"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
 )
@@ -27,51 +28,136 @@ func (r *Router) SetupV1Routes() {
 	r.mux.HandleFunc("GET /v1/healthz", handlers.HandlerReadiness)
 	r.mux.HandleFunc("GET /v1/err", handlers.HandlerError)
 
+	// Key Discovery
+	r.mux.HandleFunc("GET /.well-known/jwks.json", r.handlers.GetJWKS)
+
 	// AWS Routes
 	r.mux.HandleFunc("POST /v1/s3/url", r.middleware.IsAuthenticated(r.handlers.GetSignedURL))
 
 	// Auth Routes
 	r.mux.HandleFunc("POST /v1/login", r.handlers.LoginUserStandard)
+	r.mux.HandleFunc("POST /v1/login/2fa", r.handlers.CompleteTwoFactorLogin)
 	r.mux.HandleFunc("POST /v1/logout", r.handlers.LogoutUserStandard)
 	r.mux.HandleFunc("GET /v1/auth", r.middleware.IsAuthenticated(r.handlers.CheckAuthStatus))
 
+	// OAuth Routes
+	r.mux.HandleFunc("GET /v1/oauth/{provider}/login", r.handlers.OAuthLogin)
+	r.mux.HandleFunc("GET /v1/oauth/{provider}/callback", r.handlers.OAuthCallback)
+
+	// Password Routes
+	r.mux.HandleFunc("POST /v1/password/forgot", r.handlers.ForgotPassword)
+	r.mux.HandleFunc("POST /v1/password/reset", r.handlers.ResetPassword)
+
+	// Session Routes
+	r.mux.HandleFunc("GET /v1/sessions", r.middleware.IsAuthenticated(r.handlers.GetSessions))
+	r.mux.HandleFunc("DELETE /v1/sessions", r.middleware.IsAuthenticated(r.handlers.DeleteOtherSessions))
+	r.mux.HandleFunc("DELETE /v1/sessions/{sessionID}", r.middleware.IsAuthenticated(r.handlers.DeleteSession))
+
 	// User Routes
 	r.mux.HandleFunc("POST /v1/users", r.handlers.CreateUserStandard)
 	r.mux.HandleFunc("PUT /v1/users", r.middleware.IsAuthenticated(r.handlers.UpdateUser))
+	r.mux.HandleFunc("PUT /v1/users/password", r.middleware.IsAuthenticated(r.handlers.ChangePassword))
 	r.mux.HandleFunc("PUT /v1/users/avatar", r.middleware.IsAuthenticated(r.handlers.UpdateAvatar))
 	r.mux.HandleFunc("GET /v1/users/auth", r.middleware.IsAuthenticated(r.handlers.FetchAuthUser))
+	r.mux.HandleFunc("POST /v1/users/2fa/enroll", r.middleware.IsAuthenticated(r.handlers.EnrollTwoFactor))
+	r.mux.HandleFunc("POST /v1/users/2fa/confirm", r.middleware.IsAuthenticated(r.handlers.ConfirmTwoFactor))
+	r.mux.HandleFunc("DELETE /v1/users/2fa", r.middleware.IsAuthenticated(r.handlers.DisableTwoFactor))
+	r.mux.HandleFunc("POST /v1/users/verify", r.handlers.VerifyEmail)
+	r.mux.HandleFunc("POST /v1/users/verify/resend", r.middleware.IsAuthenticated(r.handlers.ResendVerificationEmail))
 	r.mux.HandleFunc("DELETE /v1/users/{userID}", r.middleware.IsAuthenticated(r.handlers.DeleteUser))
 
+	// Bot Routes
+	r.mux.HandleFunc("GET /v1/bots", r.middleware.IsAuthenticated(r.handlers.GetBots))
+	r.mux.HandleFunc("POST /v1/bots", r.middleware.IsAuthenticated(r.handlers.CreateBot))
+	r.mux.HandleFunc("DELETE /v1/bots/{botID}", r.middleware.IsAuthenticated(r.handlers.DeleteBot))
+	r.mux.HandleFunc("POST /v1/bots/{botID}/token", r.middleware.IsAuthenticated(r.handlers.ResetBotToken))
+	r.mux.HandleFunc("POST /v1/bots/{botID}/servers", r.middleware.IsAuthenticated(r.handlers.InviteBot))
+
 	// Server Routes
-	r.mux.HandleFunc("POST /v1/servers", r.middleware.IsAuthenticated(r.handlers.CreateServer))
-	r.mux.HandleFunc("POST /v1/servers/join", r.middleware.IsAuthenticated(r.handlers.JoinServerByID))
-	r.mux.HandleFunc("POST /v1/servers/code", r.middleware.IsAuthenticated(r.handlers.JoinServerByCode))
-	r.mux.HandleFunc("PUT /v1/servers", r.middleware.IsAuthenticated(r.handlers.UpdateServer))
-	r.mux.HandleFunc("PUT /v1/servers/images", r.middleware.IsAuthenticated(r.handlers.UpdateServerImages))
-	r.mux.HandleFunc("DELETE /v1/servers/user", r.middleware.IsAuthenticated(r.handlers.LeaveServer))
+	r.mux.HandleFunc("POST /v1/servers", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.middleware.RequireVerified(r.handlers.CreateServer)))
+	r.mux.HandleFunc("POST /v1/servers/join", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.JoinServerByID))
+	r.mux.HandleFunc("POST /v1/servers/code", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.JoinServerByCode))
+	r.mux.HandleFunc("PUT /v1/servers", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.UpdateServer))
+	r.mux.HandleFunc("PUT /v1/servers/images", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.UpdateServerImages))
+	r.mux.HandleFunc("DELETE /v1/servers/user", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.LeaveServer))
 	r.mux.HandleFunc("GET /v1/servers/recent", r.handlers.GetRecentServers)
-	r.mux.HandleFunc("GET /v1/servers/user/many", r.middleware.IsAuthenticated(r.handlers.GetUserServers))
+	r.mux.HandleFunc("GET /v1/servers/discover", r.handlers.DiscoverServers)
+	r.mux.HandleFunc("GET /v1/servers/user/many", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetUserServers))
 	r.mux.HandleFunc("GET /v1/servers/{serverID}", r.handlers.GetServerByID)
 	r.mux.HandleFunc("DELETE /v1/servers/{serverID}", r.middleware.IsAuthenticated(r.handlers.DeleteServer))
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/transfer", r.middleware.IsAuthenticated(r.handlers.TransferServerOwnership))
+	r.mux.HandleFunc("PUT /v1/servers/{serverID}/tags", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.UpdateServerTags))
+
+	// Invite Routes
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/invites", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetServerInvites))
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/invites", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.CreateInvite))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/invites/{code}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.RevokeInvite))
+
+	// Join Request Routes
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/join-requests", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.CreateJoinRequest))
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/join-requests", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetJoinRequests))
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/join-requests/{requestID}/approve", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.ApproveJoinRequest))
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/join-requests/{requestID}/reject", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.RejectJoinRequest))
+
+	// Role Routes
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/roles", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetServerRoles))
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/roles", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.CreateServerRole))
+	r.mux.HandleFunc("PUT /v1/servers/{serverID}/roles/{roleID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.UpdateServerRole))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/roles/{roleID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.DeleteServerRole))
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/members/{userID}/roles", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetMemberRoles))
+	r.mux.HandleFunc("PUT /v1/servers/{serverID}/members/{userID}/roles/{roleID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.AddMemberRole))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/members/{userID}/roles/{roleID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.RemoveMemberRole))
+
+	// Member Routes
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/members", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetServerMembers))
+
+	// Moderation Routes
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/members/{userID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.KickMember))
+	r.mux.HandleFunc("PUT /v1/servers/{serverID}/members/{userID}/timeout", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.TimeoutMember))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/members/{userID}/timeout", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.RemoveTimeout))
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/bans", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetServerBans))
+	r.mux.HandleFunc("PUT /v1/servers/{serverID}/bans/{userID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.BanMember))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/bans/{userID}", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.UnbanMember))
+
+	// Audit Log Routes
+	r.mux.HandleFunc("GET /v1/servers/{serverID}/audit-log", r.middleware.IsAuthenticatedScoped(common.ScopeServersRead, r.handlers.GetServerAuditLog))
+
+	// Boost Routes
+	r.mux.HandleFunc("POST /v1/servers/{serverID}/boosts", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.BoostServer))
+	r.mux.HandleFunc("DELETE /v1/servers/{serverID}/boosts", r.middleware.IsAuthenticatedScoped(common.ScopeServersWrite, r.handlers.RemoveServerBoost))
 
 	// Text Channel Routes
-	r.mux.HandleFunc("POST /v1/channels/text", r.middleware.IsAuthenticated(r.handlers.CreateTextChannel))
-	r.mux.HandleFunc("GET /v1/channels/{serverID}", r.middleware.IsAuthenticated(r.handlers.GetServerTextChannels))
-	r.mux.HandleFunc("DELETE /v1/channels/text/{channelID}", r.middleware.IsAuthenticated(r.handlers.DeleteTextChannel))
+	r.mux.HandleFunc("POST /v1/channels/text", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.CreateTextChannel))
+	r.mux.HandleFunc("GET /v1/channels/{serverID}", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsRead, r.handlers.GetServerTextChannels))
+	r.mux.HandleFunc("DELETE /v1/channels/text/{channelID}", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.DeleteTextChannel))
 
 	// Voice Channel Routes
-	r.mux.HandleFunc("POST /v1/channels/voice", r.middleware.IsAuthenticated(r.handlers.CreateVoiceChannel))
-	r.mux.HandleFunc("GET /v1/channels/voice/{serverID}", r.middleware.IsAuthenticated(r.handlers.GetServerVoiceChannels))
+	r.mux.HandleFunc("POST /v1/channels/voice", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.CreateVoiceChannel))
+	r.mux.HandleFunc("GET /v1/channels/voice/{serverID}", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsRead, r.handlers.GetServerVoiceChannels))
 	r.mux.HandleFunc("DELETE /v1/channels/voice/{userID}", r.middleware.IsAuthenticated(r.handlers.LeaveVoiceChannelByUserID))
 
+	// Webhook Routes
+	r.mux.HandleFunc("POST /v1/channels/text/{channelID}/webhooks", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.CreateWebhook))
+	r.mux.HandleFunc("GET /v1/channels/text/{channelID}/webhooks", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsRead, r.handlers.GetChannelWebhooks))
+	r.mux.HandleFunc("PATCH /v1/webhooks/{webhookID}", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.UpdateWebhook))
+	r.mux.HandleFunc("DELETE /v1/webhooks/{webhookID}", r.middleware.IsAuthenticatedScoped(common.ScopeChannelsWrite, r.handlers.DeleteWebhook))
+	r.mux.HandleFunc("POST /v1/webhooks/{webhookID}/{token}", r.handlers.ExecuteWebhook)
+
 	// Message Routes
-	r.mux.HandleFunc("GET /v1/messages/{channelID}", r.middleware.IsAuthenticated(r.handlers.GetChannelTextMessages))
+	r.mux.HandleFunc("GET /v1/messages/{channelID}", r.middleware.IsAuthenticatedScoped(common.ScopeMessagesRead, r.handlers.GetChannelTextMessages))
+	r.mux.HandleFunc("POST /v1/messages/{channelID}", r.middleware.IsAuthenticatedScoped(common.ScopeMessagesWrite, r.handlers.CreateChannelTextMessage))
 
 	// Token Routes
 	r.mux.HandleFunc("POST /v1/refresh", r.handlers.RefreshToken)
+	r.mux.HandleFunc("GET /v1/tokens", r.middleware.IsAuthenticated(r.handlers.GetPersonalAccessTokens))
+	r.mux.HandleFunc("POST /v1/tokens", r.middleware.IsAuthenticated(r.handlers.CreatePersonalAccessToken))
+	r.mux.HandleFunc("DELETE /v1/tokens/{tokenID}", r.middleware.IsAuthenticated(r.handlers.DeletePersonalAccessToken))
+
+	// WebSocket Routes
+	r.mux.HandleFunc("POST /v1/ws/ticket", r.middleware.IsAuthenticated(r.handlers.IssueWebSocketTicket))
 
 	// Upgrade to WebSocket
-	r.mux.HandleFunc("GET /ws", r.handlers.HandleWebSocketUpgrade)
+	r.mux.HandleFunc("GET /ws", r.middleware.IsWebSocketAuthenticated(r.handlers.HandleWebSocketUpgrade))
 }
 
 func (r *Router) GetHandler() http.Handler {
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+)
+
+const (
+	defaultAuditLogPageSize = 50
+	maxAuditLogPageSize     = 100
+)
+
+// Audit log actions, recorded as "<target>.<verb>".
+const (
+	auditServerUpdate        = "server.update"
+	auditServerIconUpdate    = "server.icon_update"
+	auditServerBannerUpdate  = "server.banner_update"
+	auditChannelCreate       = "channel.create"
+	auditChannelDelete       = "channel.delete"
+	auditVoiceChannelCreate  = "voice_channel.create"
+	auditRoleCreate          = "role.create"
+	auditRoleUpdate          = "role.update"
+	auditRoleDelete          = "role.delete"
+	auditMemberRoleAdd       = "member.role_add"
+	auditMemberRoleRemove    = "member.role_remove"
+	auditMemberKick          = "member.kick"
+	auditMemberBan           = "member.ban"
+	auditMemberUnban         = "member.unban"
+	auditMemberTimeout       = "member.timeout"
+	auditMemberTimeoutRemove = "member.timeout_remove"
+	auditWebhookCreate       = "webhook.create"
+	auditWebhookUpdate       = "webhook.update"
+	auditWebhookDelete       = "webhook.delete"
+)
+
+// Audit log target types.
+const (
+	auditTargetServer       = "server"
+	auditTargetChannel      = "channel"
+	auditTargetVoiceChannel = "voice_channel"
+	auditTargetRole         = "role"
+	auditTargetMember       = "member"
+	auditTargetWebhook      = "webhook"
+)
+
+var auditActions = []string{
+	auditServerUpdate,
+	auditServerIconUpdate,
+	auditServerBannerUpdate,
+	auditChannelCreate,
+	auditChannelDelete,
+	auditVoiceChannelCreate,
+	auditRoleCreate,
+	auditRoleUpdate,
+	auditRoleDelete,
+	auditMemberRoleAdd,
+	auditMemberRoleRemove,
+	auditMemberKick,
+	auditMemberBan,
+	auditMemberUnban,
+	auditMemberTimeout,
+	auditMemberTimeoutRemove,
+	auditWebhookCreate,
+	auditWebhookUpdate,
+	auditWebhookDelete,
+}
+
+// auditEntry describes one change for the audit log. Before and After are
+// marshalled to JSON; leave them nil when there is nothing to show, as with
+// the state before a create.
+type auditEntry struct {
+	ServerID   uuid.UUID
+	ActorID    uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   uuid.UUID
+	Before     any
+	After      any
+	Reason     string
+}
+
+// recordAudit writes an entry to the server's audit log. Pass the
+// transaction that makes the change so the entry commits or rolls back
+// with it.
+func recordAudit(ctx context.Context, db DBInterface, entry auditEntry) error {
+	before, err := auditSnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := auditSnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	return db.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		ID:       uuid.New(),
+		ServerID: entry.ServerID,
+		ActorID: uuid.NullUUID{
+			UUID:  entry.ActorID,
+			Valid: entry.ActorID != uuid.Nil,
+		},
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID: uuid.NullUUID{
+			UUID:  entry.TargetID,
+			Valid: entry.TargetID != uuid.Nil,
+		},
+		Before: before,
+		After:  after,
+		Reason: sql.NullString{
+			String: entry.Reason,
+			Valid:  entry.Reason != "",
+		},
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func auditSnapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return json.RawMessage("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+// GetServerAuditLog lists a server's audit log a page at a time, newest
+// first. The optional actor and action parameters narrow it to one user or
+// one kind of change, and since and until (RFC 3339) to a time window. Pass
+// next_cursor back as cursor to fetch the following page.
+func (h *Handlers) GetServerAuditLog(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	query := r.URL.Query()
+
+	pageSize := defaultAuditLogPageSize
+	if limit := query.Get("limit"); limit != "" {
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxAuditLogPageSize {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+	}
+
+	beforeCreatedAt, beforeID, err := decodeAuditLogCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	params := database.GetServerAuditLogParams{
+		ServerID:        serverID,
+		BeforeCreatedAt: beforeCreatedAt,
+		BeforeID:        beforeID,
+		PageSize:        int32(pageSize + 1),
+	}
+
+	if actor := query.Get("actor"); actor != "" {
+		params.ActorID.UUID, err = uuid.Parse(actor)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid actor ID")
+			return
+		}
+		params.ActorID.Valid = true
+	}
+
+	if action := query.Get("action"); action != "" {
+		if !slices.Contains(auditActions, action) {
+			respondWithError(w, http.StatusBadRequest, "Unknown action")
+			return
+		}
+		params.Action = sql.NullString{String: action, Valid: true}
+	}
+
+	params.Since, ok = parseAuditLogTime(w, query.Get("since"), "since")
+	if !ok {
+		return
+	}
+
+	params.Until, ok = parseAuditLogTime(w, query.Get("until"), "until")
+	if !ok {
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, permissions.ViewAuditLog); !ok {
+		return
+	}
+
+	// One extra row tells us whether another page follows.
+	entries, err := h.DB.GetServerAuditLog(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+
+	response := AuditLogResponse{
+		ServerID: serverID,
+		Entries:  make([]AuditLogEntry, 0, len(entries)),
+	}
+
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		last := entries[pageSize-1]
+		response.NextCursor = encodeAuditLogCursor(last.CreatedAt, last.ID)
+	}
+
+	for _, entry := range entries {
+		simple := AuditLogEntry{
+			ID:          entry.ID,
+			ActorHandle: entry.ActorHandle.String,
+			Action:      entry.Action,
+			TargetType:  entry.TargetType,
+			Before:      entry.Before,
+			After:       entry.After,
+			Reason:      entry.Reason.String,
+			CreatedAt:   entry.CreatedAt,
+		}
+		if entry.ActorID.Valid {
+			simple.ActorID = &entry.ActorID.UUID
+		}
+		if entry.TargetID.Valid {
+			simple.TargetID = &entry.TargetID.UUID
+		}
+		response.Entries = append(response.Entries, simple)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// parseAuditLogTime reads an optional RFC 3339 bound on the audit log.
+func parseAuditLogTime(w http.ResponseWriter, value, name string) (sql.NullTime, bool) {
+	if value == "" {
+		return sql.NullTime{}, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+		return sql.NullTime{}, false
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}, true
+}
+
+// encodeAuditLogCursor packs the position of the last entry on a page. The ID
+// breaks ties between entries written in the same microsecond.
+func encodeAuditLogCursor(createdAt time.Time, entryID uuid.UUID) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + ":" + entryID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAuditLogCursor(cursor string) (sql.NullTime, uuid.UUID, error) {
+	if cursor == "" {
+		return sql.NullTime{}, uuid.Nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullTime{}, uuid.Nil, errInvalidCursor
+	}
+
+	micros, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return sql.NullTime{}, uuid.Nil, errInvalidCursor
+	}
+
+	unixMicro, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return sql.NullTime{}, uuid.Nil, errInvalidCursor
+	}
+
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return sql.NullTime{}, uuid.Nil, errInvalidCursor
+	}
+
+	return sql.NullTime{Time: time.UnixMicro(unixMicro).UTC(), Valid: true}, entryID, nil
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
 	"github.com/jimmyvallejo/gleamspeak-api/utils"
@@ -17,11 +22,6 @@ type LoginUserRequest struct {
 }
 
 func (h *Handlers) LoginUserStandard(w http.ResponseWriter, r *http.Request) {
-	const (
-		accessTokenExpirySeconds  = 900
-		refreshTokenExpirySeconds = 604800
-	)
-
 	request := LoginUserRequest{}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -30,38 +30,172 @@ func (h *Handlers) LoginUserStandard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := h.clientIP(r)
+
+	if wait := h.loginRetryAfter(loginEmailSubject(request.Email), loginIPSubject(ip)); wait > 0 {
+		setRetryAfter(w, wait)
+		respondWithError(w, http.StatusTooManyRequests, "Too many login attempts")
+		return
+	}
+
 	user, err := h.DB.GetUserByEmail(r.Context(), request.Email)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Email or password incorrect")
+		h.respondLoginFailure(w, r, request.Email, ip, nil)
 		return
 	}
 
+	// Accounts created through an identity provider have no password.
 	if !user.Password.Valid {
-		respondWithError(w, http.StatusInternalServerError, "Invalid user data")
+		h.respondLoginFailure(w, r, request.Email, ip, &user)
 		return
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password.String), []byte(request.Password))
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Email or password incorrect")
+		h.respondLoginFailure(w, r, request.Email, ip, &user)
 		return
 	}
 
-	accessToken, err := utils.CreateToken(user.ID, h.JWT, 900)
+	h.resetLoginFailures(request.Email)
+
+	enabled, err := h.twoFactorEnabled(r.Context(), user.ID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		respondWithError(w, http.StatusInternalServerError, "Error checking two-factor status")
+		return
+	}
+
+	if enabled {
+		challenge, err := h.issueTwoFactorChallenge(user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error issuing challenge")
+			return
+		}
+
+		response := TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresIn:         int(twoFactorChallengeExpiry.Seconds()),
+		}
+
+		respondWithJSON(w, http.StatusOK, response)
 		return
 	}
-	refreshToken, err := utils.CreateToken(user.ID, h.JWT, 604800)
+
+	err = h.startSession(w, r, user.ID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
 		return
 	}
 
+	respondNoBody(w, http.StatusOK)
+}
+
+func (h *Handlers) respondLoginFailure(w http.ResponseWriter, r *http.Request, email, ip string, user *database.User) {
+	if wait := h.recordLoginFailure(r.Context(), email, ip, user); wait > 0 {
+		setRetryAfter(w, wait)
+	}
+	respondWithError(w, http.StatusNotFound, "Email or password incorrect")
+}
+
+// startSession records a new login session for the user and sets the access
+// and refresh token cookies bound to it.
+func (h *Handlers) startSession(w http.ResponseWriter, r *http.Request, userID uuid.UUID) error {
+	now := time.Now().UTC()
+
+	var stored database.RefreshToken
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		session, err := db.CreateSession(r.Context(), database.CreateSessionParams{
+			ID:     uuid.New(),
+			UserID: userID,
+			UserAgent: sql.NullString{
+				String: r.UserAgent(),
+				Valid:  r.UserAgent() != "",
+			},
+			IpAddress: sql.NullString{
+				String: h.clientIP(r),
+				Valid:  true,
+			},
+			CreatedAt:  now,
+			LastUsedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		stored, err = h.createRefreshToken(r.Context(), db, uuid.New(), session.ID, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := utils.CreateToken(userID, stored.FamilyID, h.JWT, accessTokenExpirySeconds)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := h.signRefreshToken(stored)
+	if err != nil {
+		return err
+	}
+
 	utils.SetTokenCookie(w, "access_token", accessToken, accessTokenExpirySeconds)
 	utils.SetTokenCookie(w, "refresh_token", refreshToken, refreshTokenExpirySeconds)
 
-	respondNoBody(w, http.StatusOK)
+	return nil
+}
+
+// confirmIdentity re-authenticates the user for sensitive actions with either
+// their current password or a second factor, writing the error response when
+// it fails. Wrong guesses count toward the same per-user limit as second
+// factors at login, so a stolen session cannot guess without limit.
+func (h *Handlers) confirmIdentity(w http.ResponseWriter, r *http.Request, user database.User, password, code, recoveryCode string) bool {
+	subject := loginUserSubject(user.ID)
+
+	if wait := h.loginRetryAfter(subject); wait > 0 {
+		setRetryAfter(w, wait)
+		respondWithError(w, http.StatusTooManyRequests, "Too many attempts")
+		return false
+	}
+
+	if password == "" && code == "" && recoveryCode == "" {
+		respondWithError(w, http.StatusForbidden, "Password or two-factor code required")
+		return false
+	}
+
+	confirmed, err := h.checkIdentity(r.Context(), user, password, code, recoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify identity")
+		return false
+	}
+
+	if !confirmed {
+		if wait, _ := h.registerLoginFailure(subject, h.LoginLimits.MaxAttempts); wait > 0 {
+			setRetryAfter(w, wait)
+		}
+		respondWithError(w, http.StatusForbidden, "Incorrect password or code")
+		return false
+	}
+
+	h.resetTwoFactorFailures(user.ID)
+	return true
+}
+
+func (h *Handlers) checkIdentity(ctx context.Context, user database.User, password, code, recoveryCode string) (bool, error) {
+	if password == "" {
+		return h.verifySecondFactor(ctx, user.ID, code, recoveryCode)
+	}
+
+	// The cached user may be stale, so compare against the stored hash.
+	current, err := h.DB.GetUserByEmail(ctx, user.Email)
+	if err != nil {
+		return false, err
+	}
+
+	if !current.Password.Valid {
+		return false, nil
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(current.Password.String), []byte(password)) == nil, nil
 }
 
 func (h *Handlers) CheckAuthStatus(w http.ResponseWriter, r *http.Request) {
@@ -84,23 +218,38 @@ func (h *Handlers) CheckAuthStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) LogoutUserStandard(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("refresh_token")
+	if err == nil {
+		h.revokeRefreshTokenCookie(r.Context(), cookie.Value)
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	clearAuthCookies(w)
 	respondNoBody(w, http.StatusOK)
 }
+
+func (h *Handlers) revokeRefreshTokenCookie(ctx context.Context, value string) {
+	validated, err := utils.ValidateToken(value, h.JWT)
+	if err != nil {
+		return
+	}
+
+	claims, ok := validated.Claims.(*utils.Claims)
+	if !ok {
+		return
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return
+	}
+
+	stored, err := h.getRefreshToken(ctx, tokenID)
+	if err != nil {
+		return
+	}
+
+	err = h.revokeSession(ctx, stored.UserID, stored.FamilyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to revoke session %s: %v", stored.FamilyID, err)
+	}
+}
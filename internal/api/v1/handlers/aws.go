@@ -10,13 +10,21 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/levels"
 )
 
 type signedURLRequest struct {
 	Filename string `json:"filename"`
 	Filetype string `json:"filetype"`
+	// Filesize is required. It is checked against the size limit and signed
+	// into the URL, so S3 rejects an upload of any other length.
+	Filesize int64 `json:"filesize"`
+	// ServerID is the server the file is shared in. Its level sets the size
+	// limit; without one the unboosted limit applies.
+	ServerID uuid.NullUUID `json:"server_id"`
 }
 
 func (h *Handlers) GetSignedURL(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +41,30 @@ func (h *Handlers) GetSignedURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if request.Filesize <= 0 {
+		respondWithError(w, http.StatusBadRequest, "filesize is required")
+		return
+	}
+
+	limit := levels.Get(0).UploadLimit
+	if request.ServerID.Valid {
+		if _, ok := h.serverMember(w, r, user.ID, request.ServerID.UUID); !ok {
+			return
+		}
+
+		server, err := h.DB.GetOneServerByID(r.Context(), request.ServerID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Server not found")
+			return
+		}
+		limit = levels.Get(server.ServerLevel.Int32).UploadLimit
+	}
+
+	if request.Filesize > limit {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Files can be at most %d MB", limit>>20))
+		return
+	}
+
 	timestamp := time.Now().UnixNano()
 	uniqueID := generateUniqueID()
 	ext := path.Ext(request.Filename)
@@ -47,6 +79,8 @@ func (h *Handlers) GetSignedURL(w http.ResponseWriter, r *http.Request) {
 			Bucket:      aws.String("gleamspeak-bucket"),
 			Key:         aws.String(key),
 			ContentType: aws.String(request.Filetype),
+			// Signing the length stops the URL being reused for a larger file.
+			ContentLength: aws.Int64(request.Filesize),
 		},
 		s3.WithPresignExpires(time.Minute*15),
 	)
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/levels"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+// BoostServer adds the caller's boost to a server they belong to. Each member
+// can boost a server once, and enough boosts raise its level.
+func (h *Handlers) BoostServer(w http.ResponseWriter, r *http.Request) {
+	user, serverID, ok := h.boostTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if user.IsBot {
+		respondWithError(w, http.StatusForbidden, "Bots cannot boost servers")
+		return
+	}
+
+	server, err := h.DB.BoostServer(r.Context(), database.BoostServerParams{
+		ServerID:        serverID,
+		UserID:          user.ID,
+		CreatedAt:       time.Now().UTC(),
+		LevelThresholds: levels.Thresholds(),
+		LevelMaxMembers: levels.MaxMembers(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Already boosting this server")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to boost server")
+		}
+		return
+	}
+
+	h.respondBoostChange(w, r, server, http.StatusCreated)
+}
+
+// RemoveServerBoost withdraws the caller's boost. The server keeps its
+// members if its cap drops below them.
+func (h *Handlers) RemoveServerBoost(w http.ResponseWriter, r *http.Request) {
+	user, serverID, ok := h.boostTarget(w, r)
+	if !ok {
+		return
+	}
+
+	server, err := h.DB.RemoveServerBoost(r.Context(), database.RemoveServerBoostParams{
+		ServerID:        serverID,
+		UserID:          user.ID,
+		LevelThresholds: levels.Thresholds(),
+		LevelMaxMembers: levels.MaxMembers(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Not boosting this server")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove boost")
+		}
+		return
+	}
+
+	h.respondBoostChange(w, r, server, http.StatusOK)
+}
+
+// boostTarget parses the server from the path and checks the caller is a
+// member of it.
+func (h *Handlers) boostTarget(w http.ResponseWriter, r *http.Request) (database.User, uuid.UUID, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, uuid.Nil, false
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return database.User{}, uuid.Nil, false
+	}
+
+	if _, ok := h.serverMember(w, r, user.ID, serverID); !ok {
+		return database.User{}, uuid.Nil, false
+	}
+
+	return user, serverID, true
+}
+
+// respondBoostChange tells connected members about the server's new boost
+// state and returns it.
+func (h *Handlers) respondBoostChange(w http.ResponseWriter, r *http.Request, server database.Server, status int) {
+	respondWithJSON(w, status, h.notifyBoostChange(r, server))
+}
+
+func (h *Handlers) notifyBoostChange(r *http.Request, server database.Server) SimpleServer {
+	response := simpleServer(server)
+
+	err := h.Ws.SendToServer(r.Context(), server.ID, websocket.EventServerUpdated, response)
+	if err != nil {
+		log.Printf("Failed to broadcast boost change for %s: %v", server.ID, err)
+	}
+
+	return response
+}
+
+// dropMemberBoost withdraws the boost of a member who is leaving or being
+// removed, so it stops counting toward the server's level. Run it in the
+// transaction that removes the membership. It reports whether there was a
+// boost to drop along with the server's new state.
+func dropMemberBoost(ctx context.Context, db DBInterface, userID, serverID uuid.UUID) (database.Server, bool, error) {
+	server, err := db.RemoveServerBoost(ctx, database.RemoveServerBoostParams{
+		ServerID:        serverID,
+		UserID:          userID,
+		LevelThresholds: levels.Thresholds(),
+		LevelMaxMembers: levels.MaxMembers(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Server{}, false, nil
+		}
+		return database.Server{}, false, err
+	}
+	return server, true, nil
+}
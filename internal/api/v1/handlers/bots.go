@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+// botScopes are granted to every bot token. Bots can read and post messages
+// in the servers they were invited to but cannot manage servers or channels.
+var botScopes = []string{
+	common.ScopeServersRead,
+	common.ScopeChannelsRead,
+	common.ScopeMessagesRead,
+	common.ScopeMessagesWrite,
+}
+
+type CreateBotRequest struct {
+	Handle string `json:"handle"`
+}
+
+func (h *Handlers) CreateBot(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if user.IsBot {
+		respondWithError(w, http.StatusForbidden, "Bots cannot own bots")
+		return
+	}
+
+	request := CreateBotRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	request.Handle = strings.TrimSpace(request.Handle)
+	if request.Handle == "" {
+		respondWithError(w, http.StatusBadRequest, "Handle is required")
+		return
+	}
+
+	botID := uuid.New()
+
+	var bot database.User
+	var token string
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		bot, err = db.CreateBotUser(r.Context(), database.CreateBotUserParams{
+			ID: botID,
+			// Bots never receive mail, but email is unique and required.
+			Email:  fmt.Sprintf("%s@bots.gleamspeak.invalid", botID),
+			Handle: request.Handle,
+			BotOwnerID: uuid.NullUUID{
+				UUID:  user.ID,
+				Valid: true,
+			},
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		token, err = h.issueBotToken(r.Context(), db, bot.ID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create bot")
+		return
+	}
+
+	response := BotTokenResponse{
+		Bot:   simpleBot(bot),
+		Token: token,
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handlers) GetBots(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	bots, err := h.DB.GetOwnerBots(r.Context(), uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch bots")
+		return
+	}
+
+	simpleBots := make([]SimpleBot, len(bots))
+	for i, bot := range bots {
+		simpleBots[i] = simpleBot(bot)
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleBots)
+}
+
+func (h *Handlers) ResetBotToken(w http.ResponseWriter, r *http.Request) {
+	bot, ok := h.ownedBot(w, r)
+	if !ok {
+		return
+	}
+
+	// The old token is only revoked if a new one replaces it.
+	var token string
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		err := db.DeleteUserPersonalAccessTokens(r.Context(), bot.ID)
+		if err != nil {
+			return err
+		}
+
+		token, err = h.issueBotToken(r.Context(), db, bot.ID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to reset token")
+		return
+	}
+
+	h.Ws.DisconnectUser(bot.ID, "token reset")
+
+	response := BotTokenResponse{
+		Bot:   simpleBot(bot),
+		Token: token,
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handlers) DeleteBot(w http.ResponseWriter, r *http.Request) {
+	bot, ok := h.ownedBot(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.DeleteUser(r.Context(), bot.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete bot")
+		return
+	}
+
+	h.Ws.DisconnectUser(bot.ID, "bot deleted")
+
+	err = h.RDB.Delete("user" + bot.ID.String())
+	if err != nil {
+		log.Printf("Failed to delete bot from cache: %v", err)
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+type InviteBotRequest struct {
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+// InviteBot adds one of the caller's bots to a server the caller administers.
+// The bot joins like any other member: it holds @everyone, so it can view
+// channels, send messages, connect to voice and create invites, and gets
+// more only through roles assigned to it.
+func (h *Handlers) InviteBot(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	bot, ok := h.ownedBot(w, r)
+	if !ok {
+		return
+	}
+
+	request := InviteBotRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, request.ServerID, permissions.ManageServer); !ok {
+		return
+	}
+
+	foundServer, err := h.DB.GetOneServerByID(r.Context(), request.ServerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if h.rejectBanned(w, r, bot.ID, request.ServerID) {
+		return
+	}
+
+	if serverFull(foundServer) {
+		respondWithError(w, http.StatusForbidden, "Server is full")
+		return
+	}
+
+	userServer, err := h.DB.JoinServerMember(r.Context(), database.JoinServerMemberParams{
+		ServerID: request.ServerID,
+		UserID:   bot.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusForbidden, "Server is full")
+		} else {
+			respondWithError(w, http.StatusConflict, "Bot is already a member of this server")
+		}
+		return
+	}
+
+	h.Ws.AddBotServer(bot.ID, request.ServerID)
+
+	respondWithJSON(w, http.StatusCreated, userServer)
+}
+
+// ownedBot loads the bot named in the path and checks that the caller owns
+// it, writing the error response when it does not.
+func (h *Handlers) ownedBot(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, false
+	}
+
+	botID, err := uuid.Parse(r.PathValue("botID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid bot ID")
+		return database.User{}, false
+	}
+
+	bot, err := h.DB.GetUserByID(r.Context(), botID)
+	if err != nil || !bot.IsBot || bot.BotOwnerID.UUID != user.ID {
+		respondWithError(w, http.StatusNotFound, "Bot not found")
+		return database.User{}, false
+	}
+
+	return bot, true
+}
+
+func (h *Handlers) issueBotToken(ctx context.Context, db DBInterface, botID uuid.UUID) (string, error) {
+	token, err := utils.GenerateBotToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		ID:        uuid.New(),
+		UserID:    botID,
+		Name:      "bot",
+		TokenHash: utils.HashToken(token),
+		Scopes:    botScopes,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func simpleBot(bot database.User) SimpleBot {
+	return SimpleBot{
+		ID:        bot.ID,
+		Handle:    bot.Handle,
+		Avatar:    bot.AvatarUrl.String,
+		OwnerID:   bot.BotOwnerID.UUID,
+		CreatedAt: bot.CreatedAt,
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// TrustedProxiesFromEnv reads TRUSTED_PROXIES, a comma-separated list of
+// addresses or CIDR ranges of the reverse proxies in front of the API. It is
+// empty by default, so X-Forwarded-For is ignored unless it is set.
+func TrustedProxiesFromEnv() ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, value := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", value)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", value)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return proxies, nil
+}
+
+// clientIP returns the address a request came from. X-Forwarded-For is only
+// read when the connection comes from a trusted proxy, since anyone else can
+// set it to whatever they like. The header is walked from the right and the
+// first hop that is not a trusted proxy is the client.
+func (h *Handlers) clientIP(r *http.Request) string {
+	remote := remoteIP(r)
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 || !h.trustedProxy(remote) {
+		return remote
+	}
+
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// A malformed hop was not written by one of our proxies.
+			return remote
+		}
+		if !h.trustedProxy(hop) || i == 0 {
+			return hop
+		}
+	}
+	return remote
+}
+
+func (h *Handlers) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, proxy := range h.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,9 +1,9 @@
 package handlers
 
+// defaultRoleName is the name of the role every server member holds.
+const defaultRoleName = "@everyone"
+
 const (
-	serverOwner     = "owner"
-	serverAdmin     = "admin"
-	serverModerator = "moderator"
-	serverUser      = "user"
+	accessTokenExpirySeconds  = 900
+	refreshTokenExpirySeconds = 604800
 )
-
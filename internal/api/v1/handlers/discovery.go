@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+)
+
+const (
+	defaultDiscoveryPageSize = 20
+	maxDiscoveryPageSize     = 50
+	maxServerTags            = 5
+
+	discoveryCachePrefix = "discover:"
+	discoveryCacheExpiry = time.Minute
+)
+
+var (
+	discoverySorts   = []string{"members", "activity", "newest"}
+	serverTagPattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+)
+
+// DiscoverServers searches public servers. Every parameter is optional:
+// q is a full-text search over name and description, tag and language
+// narrow the results, and sort is one of members (the default), activity or
+// newest. Pages are cached briefly, so popular queries rarely reach the
+// database and new servers show up within a minute.
+func (h *Handlers) DiscoverServers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = discoverySorts[0]
+	}
+	if !slices.Contains(discoverySorts, sort) {
+		respondWithError(w, http.StatusBadRequest, "sort must be members, activity or newest")
+		return
+	}
+
+	pageSize := defaultDiscoveryPageSize
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxDiscoveryPageSize {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 50")
+			return
+		}
+	}
+
+	cursor := query.Get("cursor")
+	afterKey, afterID, err := decodeDiscoveryCursor(cursor)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	params := database.DiscoverServersParams{
+		Sort:     sort,
+		Query:    strings.TrimSpace(query.Get("q")),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Language: strings.ToLower(strings.TrimSpace(query.Get("language"))),
+		AfterKey: afterKey,
+		AfterID:  afterID,
+		PageSize: int32(pageSize + 1),
+	}
+
+	cacheKey := discoveryCachePrefix + url.Values{
+		"q":        {params.Query},
+		"tag":      {params.Tag},
+		"language": {params.Language},
+		"sort":     {sort},
+		"cursor":   {cursor},
+		"limit":    {strconv.Itoa(pageSize)},
+	}.Encode()
+
+	var response DiscoverServersResponse
+	if err := h.RDB.GetJSON(cacheKey, &response); err == nil {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// One extra row tells us whether another page follows.
+	servers, err := h.DB.DiscoverServers(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search servers")
+		return
+	}
+
+	response = DiscoverServersResponse{
+		Servers: make([]SimpleDiscoveredServer, 0, len(servers)),
+	}
+
+	if len(servers) > pageSize {
+		servers = servers[:pageSize]
+		last := servers[pageSize-1]
+		response.NextCursor = encodeDiscoveryCursor(last.SortKey, last.ID)
+	}
+
+	for _, server := range servers {
+		response.Servers = append(response.Servers, SimpleDiscoveredServer{
+			SimpleRecentServer: SimpleRecentServer{
+				ServerID:        server.ID,
+				ServerName:      server.ServerName,
+				Description:     server.Description.String,
+				IconURL:         server.IconUrl.String,
+				BannerURL:       server.BannerUrl.String,
+				MemberCount:     server.MemberCount.Int32,
+				ServerCreatedAt: server.CreatedAt,
+				ServerUpdatedAt: server.UpdatedAt,
+				OwnerHandle:     server.Handle,
+				OwnerAvatar:     server.AvatarUrl.String,
+			},
+			Tags: server.Tags,
+		})
+	}
+
+	if err := h.RDB.SetJson(cacheKey, response, discoveryCacheExpiry); err != nil {
+		log.Printf("Failed to cache discovery results: %v", err)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+type UpdateServerTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// UpdateServerTags replaces the tags a server is listed under in discovery.
+func (h *Handlers) UpdateServerTags(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	request := UpdateServerTagsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	tags := make([]string, 0, len(request.Tags))
+	for _, tag := range request.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !serverTagPattern.MatchString(tag) {
+			respondWithError(w, http.StatusBadRequest, "Tags must be 1-32 letters, digits or dashes")
+			return
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) > maxServerTags {
+		respondWithError(w, http.StatusBadRequest, "A server can have at most 5 tags")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, permissions.ManageServer); !ok {
+		return
+	}
+
+	err = h.DB.SetServerTags(r.Context(), database.SetServerTagsParams{
+		ServerID: serverID,
+		Tags:     tags,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update tags")
+		return
+	}
+
+	slices.Sort(tags)
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+// encodeDiscoveryCursor packs the sort key of the last server on a page. The
+// ID breaks ties between servers with the same key.
+func encodeDiscoveryCursor(key float64, serverID uuid.UUID) string {
+	raw := strconv.FormatFloat(key, 'g', -1, 64) + ":" + serverID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeDiscoveryCursor(cursor string) (sql.NullFloat64, uuid.UUID, error) {
+	if cursor == "" {
+		return sql.NullFloat64{}, uuid.Nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.Nil, errInvalidCursor
+	}
+
+	key, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return sql.NullFloat64{}, uuid.Nil, errInvalidCursor
+	}
+
+	sortKey, err := strconv.ParseFloat(key, 64)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.Nil, errInvalidCursor
+	}
+
+	serverID, err := uuid.Parse(id)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.Nil, errInvalidCursor
+	}
+
+	return sql.NullFloat64{Float64: sortKey, Valid: true}, serverID, nil
+}
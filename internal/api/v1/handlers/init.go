@@ -2,15 +2,22 @@ package handlers
 
 import (
 	"context"
+	"net/netip"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/oidc"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/redis"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
 )
 
 type DBInterface interface {
+	RunInTx(ctx context.Context, fn func(DBInterface) error) error
+
 	CreateUserStandard(ctx context.Context, params database.CreateUserStandardParams) (database.User, error)
 	GetRoleIDByName(ctx context.Context, name string) (uuid.UUID, error)
 	CreateUserRoles(ctx context.Context, params database.CreateUserRolesParams) (database.UserRole, error)
@@ -18,49 +25,168 @@ type DBInterface interface {
 	DeleteUser(ctx context.Context, id uuid.UUID) error
 	UpdateUserByID(ctx context.Context, arg database.UpdateUserByIDParams) (database.User, error)
 	UpdateUserAvatarByID(ctx context.Context, arg database.UpdateUserAvatarByIDParams) (database.User, error)
+	SetUserVerified(ctx context.Context, arg database.SetUserVerifiedParams) (database.User, error)
+	UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) (database.User, error)
+
+	CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) (database.EmailVerificationToken, error)
+	UseEmailVerificationToken(ctx context.Context, arg database.UseEmailVerificationTokenParams) (database.EmailVerificationToken, error)
+
+	CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) (database.PasswordResetToken, error)
+	UsePasswordResetToken(ctx context.Context, arg database.UsePasswordResetTokenParams) (database.PasswordResetToken, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, arg database.InvalidateUserPasswordResetTokensParams) error
+
+	UpsertUserTOTP(ctx context.Context, arg database.UpsertUserTOTPParams) (database.UserTotp, error)
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error)
+	EnableUserTOTP(ctx context.Context, arg database.EnableUserTOTPParams) (database.UserTotp, error)
+	UpdateUserTOTPLastUsedStep(ctx context.Context, arg database.UpdateUserTOTPLastUsedStepParams) (int64, error)
+	DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error
+	CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error
+	UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error)
+	DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+
+	GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error)
+	CreateUserIdentity(ctx context.Context, arg database.CreateUserIdentityParams) (database.UserIdentity, error)
+	GetUserIdentity(ctx context.Context, arg database.GetUserIdentityParams) (database.UserIdentity, error)
+	TouchUserIdentity(ctx context.Context, arg database.TouchUserIdentityParams) error
+
+	CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error)
+	GetUserPersonalAccessTokens(ctx context.Context, userID uuid.UUID) ([]database.PersonalAccessToken, error)
+	DeletePersonalAccessToken(ctx context.Context, arg database.DeletePersonalAccessTokenParams) (int64, error)
+	DeleteUserPersonalAccessTokens(ctx context.Context, userID uuid.UUID) error
+
+	CreateBotUser(ctx context.Context, arg database.CreateBotUserParams) (database.User, error)
+	GetOwnerBots(ctx context.Context, botOwnerID uuid.NullUUID) ([]database.User, error)
 
 	CreateServer(ctx context.Context, arg database.CreateServerParams) (database.Server, error)
 	CreateUserServer(ctx context.Context, arg database.CreateUserServerParams) (database.UserServer, error)
-	UpdateServerMemberCount(ctx context.Context, arg database.UpdateServerMemberCountParams) (database.UpdateServerMemberCountRow, error)
+	JoinServerMember(ctx context.Context, arg database.JoinServerMemberParams) (database.UserServer, error)
 	UpdateServerBannerByID(ctx context.Context, arg database.UpdateServerBannerByIDParams) (database.UpdateServerBannerByIDRow, error)
 	UpdateServerIconByID(ctx context.Context, arg database.UpdateServerIconByIDParams) (database.UpdateServerIconByIDRow, error)
 	UpdateServerByID(ctx context.Context, arg database.UpdateServerByIDParams) (database.Server, error)
 	DeleteServer(ctx context.Context, id uuid.UUID) error
+	TransferServerOwnership(ctx context.Context, arg database.TransferServerOwnershipParams) (database.TransferServerOwnershipRow, error)
 
 	GetUserServers(ctx context.Context, userID uuid.UUID) ([]database.GetUserServersRow, error)
 	GetUserServer(ctx context.Context, arg database.GetUserServerParams) (database.UserServer, error)
+	GetServerMembers(ctx context.Context, arg database.GetServerMembersParams) ([]database.GetServerMembersRow, error)
 	GetOneServerByID(ctx context.Context, id uuid.UUID) (database.Server, error)
 	GetOneServerByCode(ctx context.Context, inviteCode string) (database.Server, error)
 	GetRecentServers(ctx context.Context) ([]database.GetRecentServersRow, error)
+	DiscoverServers(ctx context.Context, arg database.DiscoverServersParams) ([]database.DiscoverServersRow, error)
+	SetServerTags(ctx context.Context, arg database.SetServerTagsParams) error
+	BoostServer(ctx context.Context, arg database.BoostServerParams) (database.Server, error)
+	RemoveServerBoost(ctx context.Context, arg database.RemoveServerBoostParams) (database.Server, error)
+	GetUserBoostedServerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 	DeleteUserServer(ctx context.Context, arg database.DeleteUserServerParams) error
 
+	CreateServerRole(ctx context.Context, arg database.CreateServerRoleParams) (database.ServerRole, error)
+	GetServerRoles(ctx context.Context, serverID uuid.UUID) ([]database.ServerRole, error)
+	GetServerRole(ctx context.Context, arg database.GetServerRoleParams) (database.ServerRole, error)
+	UpdateServerRole(ctx context.Context, arg database.UpdateServerRoleParams) (database.ServerRole, error)
+	DeleteServerRole(ctx context.Context, arg database.DeleteServerRoleParams) (int64, error)
+	AddMemberRole(ctx context.Context, arg database.AddMemberRoleParams) error
+	RemoveMemberRole(ctx context.Context, arg database.RemoveMemberRoleParams) (int64, error)
+	GetMemberRoles(ctx context.Context, arg database.GetMemberRolesParams) ([]database.ServerRole, error)
+	GetMemberPermissions(ctx context.Context, arg database.GetMemberPermissionsParams) (database.GetMemberPermissionsRow, error)
+
+	CreateInvite(ctx context.Context, arg database.CreateInviteParams) (database.Invite, error)
+	GetServerInvites(ctx context.Context, serverID uuid.UUID) ([]database.Invite, error)
+	GetInviteByCode(ctx context.Context, code string) (database.Invite, error)
+	RevokeInvite(ctx context.Context, arg database.RevokeInviteParams) (int64, error)
+	JoinServerWithInvite(ctx context.Context, arg database.JoinServerWithInviteParams) (database.UserServer, error)
+
+	CreateJoinRequest(ctx context.Context, arg database.CreateJoinRequestParams) (database.JoinRequest, error)
+	GetPendingJoinRequests(ctx context.Context, serverID uuid.UUID) ([]database.GetPendingJoinRequestsRow, error)
+	ApproveJoinRequest(ctx context.Context, arg database.ApproveJoinRequestParams) (database.ApproveJoinRequestRow, error)
+	RejectJoinRequest(ctx context.Context, arg database.RejectJoinRequestParams) (database.JoinRequest, error)
+
+	RemoveServerMember(ctx context.Context, arg database.RemoveServerMemberParams) (int64, error)
+	BanMember(ctx context.Context, arg database.BanMemberParams) (database.BanMemberRow, error)
+	UnbanMember(ctx context.Context, arg database.UnbanMemberParams) (int64, error)
+	GetActiveBan(ctx context.Context, arg database.GetActiveBanParams) (database.ServerBan, error)
+	GetServerBans(ctx context.Context, arg database.GetServerBansParams) ([]database.GetServerBansRow, error)
+	SetMemberMutedUntil(ctx context.Context, arg database.SetMemberMutedUntilParams) (int64, error)
+
+	CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error
+	GetServerAuditLog(ctx context.Context, arg database.GetServerAuditLogParams) ([]database.GetServerAuditLogRow, error)
+
 	CreateTextChannel(ctx context.Context, arg database.CreateTextChannelParams) (database.TextChannel, error)
 	DeleteTextChannel(ctx context.Context, id uuid.UUID) error
 	GetServerTextChannels(ctx context.Context, serverID uuid.UUID) ([]database.TextChannel, error)
 	GetLanguageIDByName(ctx context.Context, language string) (uuid.UUID, error)
 
+	GetTextChannelByID(ctx context.Context, id uuid.UUID) (database.TextChannel, error)
+
 	CreateTextMessage(ctx context.Context, arg database.CreateTextMessageParams) (database.TextMessage, error)
+	CreateWebhookMessage(ctx context.Context, arg database.CreateWebhookMessageParams) (database.TextMessage, error)
 	GetChannelTextMessages(ctx context.Context, channelID uuid.UUID) ([]database.GetChannelTextMessagesRow, error)
 
+	CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error)
+	GetWebhookByID(ctx context.Context, id uuid.UUID) (database.Webhook, error)
+	GetWebhookByToken(ctx context.Context, arg database.GetWebhookByTokenParams) (database.GetWebhookByTokenRow, error)
+	GetChannelWebhooks(ctx context.Context, channelID uuid.UUID) ([]database.Webhook, error)
+	UpdateWebhook(ctx context.Context, arg database.UpdateWebhookParams) (database.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+
 	CreateVoiceChannel(ctx context.Context, arg database.CreateVoiceChannelParams) (database.VoiceChannel, error)
 	GetServerVoiceChannels(ctx context.Context, serverID uuid.UUID) ([]database.GetServerVoiceChannelsRow, error)
 	LeaveVoiceChannelByUser(ctx context.Context, userID uuid.UUID) error
+	GetUserVoiceChannelMemberships(ctx context.Context, userID uuid.UUID) ([]database.VoiceChannelMember, error)
+	LeaveServerVoiceChannels(ctx context.Context, arg database.LeaveServerVoiceChannelsParams) error
+
+	CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error)
+	GetRefreshTokenByID(ctx context.Context, id uuid.UUID) (database.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (int64, error)
+	RevokeRefreshTokenFamily(ctx context.Context, arg database.RevokeRefreshTokenFamilyParams) ([]uuid.UUID, error)
+
+	CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error)
+	GetActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]database.Session, error)
+	TouchSession(ctx context.Context, arg database.TouchSessionParams) error
+	RevokeSession(ctx context.Context, arg database.RevokeSessionParams) (database.Session, error)
+	RevokeUserSessionsExcept(ctx context.Context, arg database.RevokeUserSessionsExceptParams) ([]uuid.UUID, error)
 }
 
+// Cache is the Redis client handlers keep short-lived state in, such as
+// cached users, refresh tokens, two-factor challenges and login failures.
+type Cache interface {
+	GetJSON(key string, dest interface{}) error
+	SetJson(key string, value interface{}, expiration time.Duration) error
+	GetDelJSON(key string, dest interface{}) error
+	Delete(keys ...string) error
+	Incr(key string, expiration time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
+}
+
+var _ Cache = (*redis.RedisClient)(nil)
+
 type Handlers struct {
-	DB  DBInterface
-	RDB *redis.RedisClient
-	JWT string
-	S3  *s3.Client
-	Ws  *websocket.Manager
+	DB     DBInterface
+	RDB    Cache
+	JWT    *jwtkeys.KeySet
+	S3     *s3.Client
+	Ws     *websocket.Manager
+	Mailer mailer.Mailer
+	AppURL string
+	OIDC   map[string]*oidc.Provider
+
+	LoginLimits LoginLimits
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// is believed when working out a client's IP.
+	TrustedProxies []netip.Prefix
 }
 
-func NewHandlers(db DBInterface, rdb *redis.RedisClient, jwt string, s3 *s3.Client, ws *websocket.Manager) *Handlers {
+func NewHandlers(db DBInterface, rdb Cache, jwt *jwtkeys.KeySet, s3 *s3.Client, ws *websocket.Manager, mail mailer.Mailer, appURL string, providers map[string]*oidc.Provider, limits LoginLimits, proxies []netip.Prefix) *Handlers {
 	return &Handlers{
-		DB:  db,
-		RDB: rdb,
-		JWT: jwt,
-		S3:  s3,
-		Ws:  ws,
+		DB:     db,
+		RDB:    rdb,
+		JWT:    jwt,
+		S3:     s3,
+		Ws:     ws,
+		Mailer: mail,
+		AppURL: appURL,
+		OIDC:   providers,
+
+		LoginLimits:    limits,
+		TrustedProxies: proxies,
 	}
 }
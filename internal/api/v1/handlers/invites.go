@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const inviteCodeLength = 10
+
+type CreateInviteRequest struct {
+	// MaxUses limits how many members can join with the invite. Zero means
+	// unlimited.
+	MaxUses int32 `json:"max_uses"`
+	// ExpiresIn is the invite lifetime in seconds. Zero means it never
+	// expires.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+func (h *Handlers) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	request := CreateInviteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if request.MaxUses < 0 || request.ExpiresIn < 0 {
+		respondWithError(w, http.StatusBadRequest, "max_uses and expires_in cannot be negative")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, permissions.CreateInvite); !ok {
+		return
+	}
+
+	code, err := utils.GenerateInviteCode(inviteCodeLength)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create invite")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	invite, err := h.DB.CreateInvite(r.Context(), database.CreateInviteParams{
+		Code:      code,
+		ServerID:  serverID,
+		CreatorID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		MaxUses: sql.NullInt32{
+			Int32: request.MaxUses,
+			Valid: request.MaxUses > 0,
+		},
+		ExpiresAt: sql.NullTime{
+			Time:  now.Add(time.Duration(request.ExpiresIn) * time.Second),
+			Valid: request.ExpiresIn > 0,
+		},
+		CreatedAt: now,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create invite")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, simpleInvite(invite))
+}
+
+func (h *Handlers) GetServerInvites(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, permissions.ManageServer); !ok {
+		return
+	}
+
+	invites, err := h.DB.GetServerInvites(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch invites")
+		return
+	}
+
+	simpleInvites := make([]SimpleInvite, len(invites))
+	for i, invite := range invites {
+		simpleInvites[i] = simpleInvite(invite)
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleInvites)
+}
+
+// RevokeInvite disables an invite. The record is kept so members who joined
+// with it stay attributed to it. Creators may revoke their own invites;
+// anyone else needs ManageServer.
+func (h *Handlers) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	invite, err := h.DB.GetInviteByCode(r.Context(), r.PathValue("code"))
+	if err != nil || invite.ServerID != serverID {
+		respondWithError(w, http.StatusNotFound, "Invite not found")
+		return
+	}
+
+	perm := permissions.ManageServer
+	if invite.CreatorID.Valid && invite.CreatorID.UUID == user.ID {
+		perm = permissions.CreateInvite
+	}
+	if _, ok := h.authorize(w, r, user.ID, serverID, perm); !ok {
+		return
+	}
+
+	revoked, err := h.DB.RevokeInvite(r.Context(), database.RevokeInviteParams{
+		Code:     invite.Code,
+		ServerID: serverID,
+		RevokedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke invite")
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Invite not found")
+		return
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+// inviteUsable reports whether an invite can still admit a member.
+func inviteUsable(invite database.Invite, now time.Time) bool {
+	if invite.RevokedAt.Valid {
+		return false
+	}
+	if invite.ExpiresAt.Valid && !now.Before(invite.ExpiresAt.Time) {
+		return false
+	}
+	if invite.MaxUses.Valid && invite.Uses >= invite.MaxUses.Int32 {
+		return false
+	}
+	return true
+}
+
+func simpleInvite(invite database.Invite) SimpleInvite {
+	simple := SimpleInvite{
+		Code:      invite.Code,
+		ServerID:  invite.ServerID,
+		Uses:      invite.Uses,
+		Revoked:   invite.RevokedAt.Valid,
+		CreatedAt: invite.CreatedAt,
+	}
+	if invite.CreatorID.Valid {
+		simple.CreatorID = &invite.CreatorID.UUID
+	}
+	if invite.MaxUses.Valid {
+		simple.MaxUses = &invite.MaxUses.Int32
+	}
+	if invite.ExpiresAt.Valid {
+		simple.ExpiresAt = &invite.ExpiresAt.Time
+	}
+	return simple
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+// reviewJoinRequests is the permission needed to see and decide join
+// requests. Moderators who can remove members also decide who gets in.
+const reviewJoinRequests = permissions.KickMembers
+
+const maxJoinRequestMessageLength = 500
+
+type CreateJoinRequestRequest struct {
+	Message string `json:"message"`
+}
+
+func (h *Handlers) CreateJoinRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	request := CreateJoinRequestRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	request.Message = strings.TrimSpace(request.Message)
+	if len(request.Message) > maxJoinRequestMessageLength {
+		respondWithError(w, http.StatusBadRequest, "Message is too long")
+		return
+	}
+
+	foundServer, err := h.DB.GetOneServerByID(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if foundServer.IsPublic.Bool {
+		respondWithError(w, http.StatusBadRequest, "Server is public, join it directly")
+		return
+	}
+
+	if h.rejectBanned(w, r, user.ID, serverID) {
+		return
+	}
+
+	_, err = h.DB.GetUserServer(r.Context(), database.GetUserServerParams{
+		UserID:   user.ID,
+		ServerID: serverID,
+	})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "Already a member of this server")
+		return
+	}
+
+	joinRequest, err := h.DB.CreateJoinRequest(r.Context(), database.CreateJoinRequestParams{
+		ID:       uuid.New(),
+		ServerID: serverID,
+		UserID:   user.ID,
+		Message: sql.NullString{
+			String: request.Message,
+			Valid:  request.Message != "",
+		},
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if isUniqueViolation(err, "idx_join_requests_pending") {
+			respondWithError(w, http.StatusConflict, "A join request is already pending")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create join request")
+		}
+		return
+	}
+
+	response := simpleJoinRequest(joinRequest)
+	response.Handle = user.Handle
+	response.Avatar = user.AvatarUrl.String
+
+	err = h.Ws.SendToPermission(r.Context(), serverID, reviewJoinRequests, websocket.EventJoinRequestCreated, response)
+	if err != nil {
+		log.Printf("Failed to notify moderators of join request %s: %v", joinRequest.ID, err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handlers) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, reviewJoinRequests); !ok {
+		return
+	}
+
+	requests, err := h.DB.GetPendingJoinRequests(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch join requests")
+		return
+	}
+
+	simpleRequests := make([]SimpleJoinRequest, len(requests))
+	for i, request := range requests {
+		simpleRequests[i] = SimpleJoinRequest{
+			ID:        request.ID,
+			ServerID:  request.ServerID,
+			UserID:    request.UserID,
+			Handle:    request.Handle,
+			Avatar:    request.AvatarUrl.String,
+			Message:   request.Message.String,
+			Status:    "pending",
+			CreatedAt: request.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleRequests)
+}
+
+func (h *Handlers) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	user, serverID, requestID, ok := h.joinRequestDecision(w, r)
+	if !ok {
+		return
+	}
+
+	approved, err := h.DB.ApproveJoinRequest(r.Context(), database.ApproveJoinRequestParams{
+		ID:        requestID,
+		ServerID:  serverID,
+		DecidedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		DecidedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Failed to approve join request")
+			return
+		}
+		// A full server leaves the request pending, so tell the two apart.
+		foundServer, err := h.DB.GetOneServerByID(r.Context(), serverID)
+		if err == nil && serverFull(foundServer) {
+			respondWithError(w, http.StatusForbidden, "Server is full")
+		} else {
+			respondWithError(w, http.StatusNotFound, "Join request not found")
+		}
+		return
+	}
+
+	h.respondJoinRequestDecision(w, simpleJoinRequest(database.JoinRequest(approved)))
+}
+
+func (h *Handlers) RejectJoinRequest(w http.ResponseWriter, r *http.Request) {
+	user, serverID, requestID, ok := h.joinRequestDecision(w, r)
+	if !ok {
+		return
+	}
+
+	rejected, err := h.DB.RejectJoinRequest(r.Context(), database.RejectJoinRequestParams{
+		ID:        requestID,
+		ServerID:  serverID,
+		DecidedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		DecidedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Join request not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to reject join request")
+		}
+		return
+	}
+
+	h.respondJoinRequestDecision(w, simpleJoinRequest(rejected))
+}
+
+// joinRequestDecision parses the path for the approve and reject routes and
+// checks that the caller may review the server's join requests.
+func (h *Handlers) joinRequestDecision(w http.ResponseWriter, r *http.Request) (database.User, uuid.UUID, uuid.UUID, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	requestID, err := uuid.Parse(r.PathValue("requestID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid join request ID")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, reviewJoinRequests); !ok {
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	return user, serverID, requestID, true
+}
+
+// respondJoinRequestDecision tells the applicant about the decision on any
+// live connection and returns the decided request.
+func (h *Handlers) respondJoinRequestDecision(w http.ResponseWriter, decided SimpleJoinRequest) {
+	err := h.Ws.SendToUser(decided.UserID, websocket.EventJoinRequestDecided, decided)
+	if err != nil {
+		log.Printf("Failed to notify applicant of join request %s: %v", decided.ID, err)
+	}
+
+	respondWithJSON(w, http.StatusOK, decided)
+}
+
+func simpleJoinRequest(request database.JoinRequest) SimpleJoinRequest {
+	simple := SimpleJoinRequest{
+		ID:        request.ID,
+		ServerID:  request.ServerID,
+		UserID:    request.UserID,
+		Message:   request.Message.String,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt,
+	}
+	if request.DecidedAt.Valid {
+		simple.DecidedAt = &request.DecidedAt.Time
+	}
+	return simple
+}
@@ -0,0 +1,10 @@
+package handlers
+
+import "net/http"
+
+// GetJWKS publishes the public signing keys so other services can verify
+// tokens issued by the API without sharing a secret.
+func (h *Handlers) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, h.JWT.JWKS())
+}
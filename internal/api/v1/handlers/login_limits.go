@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+)
+
+const (
+	loginFailurePrefix = "loginfail:"
+	loginBlockPrefix   = "loginblock:"
+)
+
+// LoginLimits controls brute-force protection for password logins and the
+// second factor that follows them. Failures are counted per email (per user
+// for second factors) and per client IP. After FreeAttempts failures each
+// further failure blocks the subject for an exponentially growing delay, and
+// reaching the max locks it for LockoutDuration.
+type LoginLimits struct {
+	FreeAttempts    int64
+	MaxAttempts     int64
+	IPMaxAttempts   int64
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+func DefaultLoginLimits() LoginLimits {
+	return LoginLimits{
+		FreeAttempts:    3,
+		MaxAttempts:     10,
+		IPMaxAttempts:   50,
+		BaseDelay:       time.Second,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+}
+
+// LoginLimitsFromEnv overrides the defaults with LOGIN_FREE_ATTEMPTS,
+// LOGIN_MAX_ATTEMPTS, LOGIN_IP_MAX_ATTEMPTS, LOGIN_BACKOFF_BASE,
+// LOGIN_LOCKOUT_DURATION and LOGIN_FAILURE_WINDOW.
+func LoginLimitsFromEnv() (LoginLimits, error) {
+	limits := DefaultLoginLimits()
+
+	ints := map[string]*int64{
+		"LOGIN_FREE_ATTEMPTS":   &limits.FreeAttempts,
+		"LOGIN_MAX_ATTEMPTS":    &limits.MaxAttempts,
+		"LOGIN_IP_MAX_ATTEMPTS": &limits.IPMaxAttempts,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed < 1 {
+				return limits, fmt.Errorf("invalid %s: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"LOGIN_BACKOFF_BASE":     &limits.BaseDelay,
+		"LOGIN_LOCKOUT_DURATION": &limits.LockoutDuration,
+		"LOGIN_FAILURE_WINDOW":   &limits.Window,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return limits, fmt.Errorf("invalid %s: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if limits.FreeAttempts >= limits.MaxAttempts {
+		return limits, fmt.Errorf("LOGIN_FREE_ATTEMPTS must be below LOGIN_MAX_ATTEMPTS")
+	}
+
+	return limits, nil
+}
+
+func loginEmailSubject(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// loginUserSubject counts failed second factors. The challenge only carries
+// the user, so they are tracked apart from password failures by email.
+func loginUserSubject(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// loginRetryAfter returns how long the caller must wait before trying to log
+// in again, or zero when none of the subjects is blocked.
+func (h *Handlers) loginRetryAfter(subjects ...string) time.Duration {
+	var wait time.Duration
+	for _, subject := range subjects {
+		ttl, err := h.RDB.TTL(loginBlockPrefix + subject)
+		if err != nil {
+			log.Printf("Failed to check login block for %s: %v", subject, err)
+			continue
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait
+}
+
+// recordLoginFailure counts a failed login for the email and IP and returns
+// the delay before the next attempt is allowed. user is nil when the email
+// does not belong to an account.
+func (h *Handlers) recordLoginFailure(ctx context.Context, email, ip string, user *database.User) time.Duration {
+	wait, locked := h.registerLoginFailure(loginEmailSubject(email), h.LoginLimits.MaxAttempts)
+	if locked && user != nil {
+		err := h.sendLockoutEmail(ctx, *user)
+		if err != nil {
+			log.Printf("Failed to send lockout email to %s: %v", user.Email, err)
+		}
+	}
+
+	ipWait, _ := h.registerLoginFailure(loginIPSubject(ip), h.LoginLimits.IPMaxAttempts)
+	if ipWait > wait {
+		wait = ipWait
+	}
+
+	return wait
+}
+
+// recordTwoFactorFailure counts a wrong second factor for the user and IP
+// and returns the delay before the next attempt is allowed.
+func (h *Handlers) recordTwoFactorFailure(userID uuid.UUID, ip string) time.Duration {
+	wait, _ := h.registerLoginFailure(loginUserSubject(userID), h.LoginLimits.MaxAttempts)
+
+	ipWait, _ := h.registerLoginFailure(loginIPSubject(ip), h.LoginLimits.IPMaxAttempts)
+	if ipWait > wait {
+		wait = ipWait
+	}
+
+	return wait
+}
+
+// registerLoginFailure increments the failure counter for subject and blocks
+// it for the resulting delay. It reports whether the subject was locked out,
+// which also resets its counter so a fresh set of attempts follows the lock.
+func (h *Handlers) registerLoginFailure(subject string, maxAttempts int64) (time.Duration, bool) {
+	limits := h.LoginLimits
+
+	failures, err := h.RDB.Incr(loginFailurePrefix+subject, limits.Window)
+	if err != nil {
+		log.Printf("Failed to count login failure for %s: %v", subject, err)
+		return 0, false
+	}
+
+	locked := failures >= maxAttempts
+
+	var wait time.Duration
+	switch {
+	case locked:
+		wait = limits.LockoutDuration
+	case failures > limits.FreeAttempts:
+		exponent := float64(failures - limits.FreeAttempts - 1)
+		wait = time.Duration(float64(limits.BaseDelay) * math.Pow(2, exponent))
+		if wait > limits.LockoutDuration {
+			wait = limits.LockoutDuration
+		}
+	default:
+		return 0, false
+	}
+
+	err = h.RDB.SetJson(loginBlockPrefix+subject, time.Now().UTC().Add(wait), wait)
+	if err != nil {
+		log.Printf("Failed to block login for %s: %v", subject, err)
+	}
+
+	if locked {
+		if err := h.RDB.Delete(loginFailurePrefix + subject); err != nil {
+			log.Printf("Failed to reset login failures for %s: %v", subject, err)
+		}
+	}
+
+	return wait, locked
+}
+
+// resetLoginFailures clears the email's failures after a successful login.
+// The IP's failures are left to expire on their own, otherwise logging into
+// one account the attacker controls would reset the limit on guessing
+// passwords for every other email from the same address.
+func (h *Handlers) resetLoginFailures(email string) {
+	err := h.RDB.Delete(
+		loginFailurePrefix+loginEmailSubject(email),
+		loginBlockPrefix+loginEmailSubject(email),
+	)
+	if err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+}
+
+// resetTwoFactorFailures clears the user's second factor failures. Like
+// resetLoginFailures, it leaves the IP's failures to expire.
+func (h *Handlers) resetTwoFactorFailures(userID uuid.UUID) {
+	err := h.RDB.Delete(
+		loginFailurePrefix+loginUserSubject(userID),
+		loginBlockPrefix+loginUserSubject(userID),
+	)
+	if err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+}
+
+func (h *Handlers) sendLockoutEmail(ctx context.Context, user database.User) error {
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your GleamSpeak account was temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nWe locked sign-in to your account for %s after too many failed login attempts.\n\nIf this was not you, reset your password at %s/forgot-password once the lock expires.",
+			user.Handle, h.LoginLimits.LockoutDuration, h.AppURL),
+	})
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
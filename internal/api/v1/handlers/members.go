@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+const (
+	defaultMemberPageSize = 50
+	maxMemberPageSize     = 100
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// GetServerMembers lists a server's members a page at a time, ordered by
+// handle. The optional q parameter filters by handle prefix and role by role
+// ID. Pass next_cursor back as cursor to fetch the following page.
+func (h *Handlers) GetServerMembers(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	query := r.URL.Query()
+
+	pageSize := defaultMemberPageSize
+	if limit := query.Get("limit"); limit != "" {
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxMemberPageSize {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+	}
+
+	afterHandle, afterID, err := decodeMemberCursor(query.Get("cursor"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+
+	roleID := uuid.NullUUID{}
+	if role := query.Get("role"); role != "" {
+		roleID.UUID, err = uuid.Parse(role)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+			return
+		}
+		roleID.Valid = true
+	}
+
+	if _, ok := h.serverMember(w, r, user.ID, serverID); !ok {
+		return
+	}
+
+	server, err := h.DB.GetOneServerByID(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	// One extra row tells us whether another page follows.
+	members, err := h.DB.GetServerMembers(r.Context(), database.GetServerMembersParams{
+		ServerID:     serverID,
+		AfterHandle:  afterHandle,
+		AfterID:      afterID,
+		HandlePrefix: escapeLikePattern(query.Get("q")),
+		RoleID:       roleID,
+		PageSize:     int32(pageSize + 1),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch members")
+		return
+	}
+
+	response := ServerMembersResponse{
+		ServerID: serverID,
+		Members:  []SimpleMember{},
+	}
+
+	if len(members) > pageSize {
+		members = members[:pageSize]
+		last := members[pageSize-1]
+		response.NextCursor = encodeMemberCursor(last.Handle, last.ID)
+	}
+
+	userIDs := make([]uuid.UUID, len(members))
+	for i, member := range members {
+		userIDs[i] = member.ID
+	}
+	online := h.Ws.Online(userIDs...)
+
+	now := time.Now().UTC()
+	for _, member := range members {
+		simple := SimpleMember{
+			UserID:  member.ID,
+			Handle:  member.Handle,
+			Avatar:  member.AvatarUrl.String,
+			IsBot:   member.IsBot,
+			IsOwner: member.ID == server.OwnerID,
+			Online:  online[member.ID],
+			RoleIDs: member.RoleIds,
+		}
+		if member.MutedUntil.Valid && member.MutedUntil.Time.After(now) {
+			simple.MutedUntil = &member.MutedUntil.Time
+		}
+		response.Members = append(response.Members, simple)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// encodeMemberCursor packs the sort key of the last member on a page. The ID
+// breaks ties between members who share a handle.
+func encodeMemberCursor(handle string, userID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(userID.String() + ":" + handle))
+}
+
+func decodeMemberCursor(cursor string) (string, uuid.UUID, error) {
+	if cursor == "" {
+		return "", uuid.Nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", uuid.Nil, errInvalidCursor
+	}
+
+	id, handle, found := strings.Cut(string(raw), ":")
+	if !found {
+		return "", uuid.Nil, errInvalidCursor
+	}
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return "", uuid.Nil, errInvalidCursor
+	}
+
+	return handle, userID, nil
+}
+
+// escapeLikePattern makes user input match literally inside a LIKE pattern.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+const (
+	maxModerationReasonLength = 500
+	maxTimeoutDuration        = 28 * 24 * time.Hour
+)
+
+var (
+	errMemberNotFound = errors.New("member not found")
+	errBanNotFound    = errors.New("ban not found")
+)
+
+// KickMember removes a member from a server. They may rejoin through any
+// usable invite.
+func (h *Handlers) KickMember(w http.ResponseWriter, r *http.Request) {
+	user, serverID, targetID, ok := h.moderationTarget(w, r, permissions.KickMembers, true)
+	if !ok {
+		return
+	}
+
+	var boosted database.Server
+	var dropped bool
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		removed, err := db.RemoveServerMember(r.Context(), database.RemoveServerMemberParams{
+			UserID:   targetID,
+			ServerID: serverID,
+		})
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return errMemberNotFound
+		}
+
+		err = db.LeaveServerVoiceChannels(r.Context(), database.LeaveServerVoiceChannelsParams{
+			UserID:   targetID,
+			ServerID: serverID,
+		})
+		if err != nil {
+			return err
+		}
+
+		boosted, dropped, err = dropMemberBoost(r.Context(), db, targetID, serverID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditMemberKick,
+			TargetType: auditTargetMember,
+			TargetID:   targetID,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errMemberNotFound) {
+			respondWithError(w, http.StatusNotFound, "Member not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to kick member")
+		}
+		return
+	}
+
+	h.dropServerMember(r, targetID, serverID, websocket.EventMemberKicked, websocket.ModerationEvent{
+		ServerID: serverID,
+	})
+	if dropped {
+		h.notifyBoostChange(r, boosted)
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+type BanMemberRequest struct {
+	Reason    string `json:"reason"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// BanMember bans a user from a server, removing them if they are a member.
+// Bans without expires_in last until lifted.
+func (h *Handlers) BanMember(w http.ResponseWriter, r *http.Request) {
+	user, serverID, targetID, ok := h.moderationTarget(w, r, permissions.BanMembers, false)
+	if !ok {
+		return
+	}
+
+	request := BanMemberRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	reason, ok := moderationReason(w, request.Reason)
+	if !ok {
+		return
+	}
+
+	if request.ExpiresIn < 0 {
+		respondWithError(w, http.StatusBadRequest, "expires_in must be positive")
+		return
+	}
+
+	if _, err := h.DB.GetUserByID(r.Context(), targetID); err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	expiresAt := sql.NullTime{}
+	if request.ExpiresIn > 0 {
+		expiresAt = sql.NullTime{
+			Time:  now.Add(time.Duration(request.ExpiresIn) * time.Second),
+			Valid: true,
+		}
+	}
+
+	var ban database.BanMemberRow
+	var boosted database.Server
+	var dropped bool
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		ban, err = db.BanMember(r.Context(), database.BanMemberParams{
+			ServerID: serverID,
+			UserID:   targetID,
+			Reason: sql.NullString{
+				String: reason,
+				Valid:  reason != "",
+			},
+			BannedBy:  uuid.NullUUID{UUID: user.ID, Valid: true},
+			ExpiresAt: expiresAt,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		if ban.RemovedMembers > 0 {
+			err = db.LeaveServerVoiceChannels(r.Context(), database.LeaveServerVoiceChannelsParams{
+				UserID:   targetID,
+				ServerID: serverID,
+			})
+			if err != nil {
+				return err
+			}
+
+			boosted, dropped, err = dropMemberBoost(r.Context(), db, targetID, serverID)
+			if err != nil {
+				return err
+			}
+		}
+
+		after := map[string]any{"expires_at": nil}
+		if expiresAt.Valid {
+			after["expires_at"] = expiresAt.Time
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditMemberBan,
+			TargetType: auditTargetMember,
+			TargetID:   targetID,
+			After:      after,
+			Reason:     reason,
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to ban member")
+		return
+	}
+
+	response := simpleBan(database.ServerBan{
+		ServerID:  ban.ServerID,
+		UserID:    ban.UserID,
+		Reason:    ban.Reason,
+		BannedBy:  ban.BannedBy,
+		ExpiresAt: ban.ExpiresAt,
+		CreatedAt: ban.CreatedAt,
+	})
+
+	if ban.RemovedMembers > 0 {
+		h.dropServerMember(r, targetID, serverID, websocket.EventMemberBanned, websocket.ModerationEvent{
+			ServerID:  serverID,
+			Reason:    reason,
+			ExpiresAt: response.ExpiresAt,
+		})
+	}
+	if dropped {
+		h.notifyBoostChange(r, boosted)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *Handlers) UnbanMember(w http.ResponseWriter, r *http.Request) {
+	user, serverID, targetID, ok := h.moderationTarget(w, r, permissions.BanMembers, false)
+	if !ok {
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		removed, err := db.UnbanMember(r.Context(), database.UnbanMemberParams{
+			ServerID: serverID,
+			UserID:   targetID,
+		})
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return errBanNotFound
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditMemberUnban,
+			TargetType: auditTargetMember,
+			TargetID:   targetID,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errBanNotFound) {
+			respondWithError(w, http.StatusNotFound, "Ban not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to unban user")
+		}
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+func (h *Handlers) GetServerBans(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, serverID, permissions.BanMembers); !ok {
+		return
+	}
+
+	bans, err := h.DB.GetServerBans(r.Context(), database.GetServerBansParams{
+		ServerID: serverID,
+		ExpiresAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch bans")
+		return
+	}
+
+	simpleBans := make([]SimpleBan, len(bans))
+	for i, ban := range bans {
+		simpleBans[i] = simpleBan(database.ServerBan{
+			ServerID:  ban.ServerID,
+			UserID:    ban.UserID,
+			Reason:    ban.Reason,
+			BannedBy:  ban.BannedBy,
+			ExpiresAt: ban.ExpiresAt,
+			CreatedAt: ban.CreatedAt,
+		})
+		simpleBans[i].Handle = ban.Handle
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleBans)
+}
+
+type TimeoutMemberRequest struct {
+	Duration int    `json:"duration"`
+	Reason   string `json:"reason"`
+}
+
+type TimeoutMemberResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	ServerID   uuid.UUID `json:"server_id"`
+	MutedUntil time.Time `json:"muted_until"`
+}
+
+// TimeoutMember stops a member from sending messages and joining voice for
+// duration seconds. Timing out an already muted member replaces the expiry.
+func (h *Handlers) TimeoutMember(w http.ResponseWriter, r *http.Request) {
+	user, serverID, targetID, ok := h.moderationTarget(w, r, permissions.TimeoutMembers, true)
+	if !ok {
+		return
+	}
+
+	request := TimeoutMemberRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	reason, ok := moderationReason(w, request.Reason)
+	if !ok {
+		return
+	}
+
+	duration := time.Duration(request.Duration) * time.Second
+	if duration <= 0 || duration > maxTimeoutDuration {
+		respondWithError(w, http.StatusBadRequest, "Duration must be between 1 second and 28 days")
+		return
+	}
+
+	mutedUntil := time.Now().UTC().Add(duration)
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		updated, err := db.SetMemberMutedUntil(r.Context(), database.SetMemberMutedUntilParams{
+			UserID:   targetID,
+			ServerID: serverID,
+			MutedUntil: sql.NullTime{
+				Time:  mutedUntil,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return errMemberNotFound
+		}
+
+		// Timed out members may not stay connected to voice.
+		err = db.LeaveServerVoiceChannels(r.Context(), database.LeaveServerVoiceChannelsParams{
+			UserID:   targetID,
+			ServerID: serverID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditMemberTimeout,
+			TargetType: auditTargetMember,
+			TargetID:   targetID,
+			After:      map[string]any{"muted_until": mutedUntil},
+			Reason:     reason,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errMemberNotFound) {
+			respondWithError(w, http.StatusNotFound, "Member not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to time out member")
+		}
+		return
+	}
+
+	err = h.Ws.SendToUser(targetID, websocket.EventMemberTimedOut, websocket.ModerationEvent{
+		ServerID:  serverID,
+		Reason:    reason,
+		ExpiresAt: &mutedUntil,
+	})
+	if err != nil {
+		log.Printf("Failed to notify %s of timeout in %s: %v", targetID, serverID, err)
+	}
+
+	respondWithJSON(w, http.StatusOK, TimeoutMemberResponse{
+		UserID:     targetID,
+		ServerID:   serverID,
+		MutedUntil: mutedUntil,
+	})
+}
+
+func (h *Handlers) RemoveTimeout(w http.ResponseWriter, r *http.Request) {
+	user, serverID, targetID, ok := h.moderationTarget(w, r, permissions.TimeoutMembers, true)
+	if !ok {
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		_, err := db.SetMemberMutedUntil(r.Context(), database.SetMemberMutedUntilParams{
+			UserID:   targetID,
+			ServerID: serverID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditMemberTimeoutRemove,
+			TargetType: auditTargetMember,
+			TargetID:   targetID,
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to remove timeout")
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+// moderationTarget parses the path for the moderation routes and checks that
+// the caller holds perm and outranks the target. When requireMember is false
+// the target may be someone outside the server, as with bans.
+func (h *Handlers) moderationTarget(w http.ResponseWriter, r *http.Request, perm permissions.Permission, requireMember bool) (database.User, uuid.UUID, uuid.UUID, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	actor, ok := h.authorize(w, r, user.ID, serverID, perm)
+	if !ok {
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	if targetID == user.ID {
+		respondWithError(w, http.StatusBadRequest, "Cannot moderate yourself")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	target, err := permissions.GetMember(r.Context(), h.DB, targetID, serverID)
+	switch {
+	case errors.Is(err, permissions.ErrNotMember):
+		if requireMember {
+			respondWithError(w, http.StatusNotFound, "Member not found")
+			return database.User{}, uuid.Nil, uuid.Nil, false
+		}
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Failed to check permissions")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	case target.IsOwner || !actor.Outranks(target.TopPosition):
+		respondWithError(w, http.StatusForbidden, "Member must be below your highest role")
+		return database.User{}, uuid.Nil, uuid.Nil, false
+	}
+
+	return user, serverID, targetID, true
+}
+
+// dropServerMember tells a member they were removed from a server and cuts
+// their live connections off from its traffic.
+func (h *Handlers) dropServerMember(r *http.Request, userID, serverID uuid.UUID, eventType string, event websocket.ModerationEvent) {
+	err := h.Ws.SendToUser(userID, eventType, event)
+	if err != nil {
+		log.Printf("Failed to notify %s of removal from %s: %v", userID, serverID, err)
+	}
+
+	h.Ws.RemoveServerMember(userID, serverID)
+}
+
+// rejectBanned responds 403 when the user holds an active ban in the server.
+func (h *Handlers) rejectBanned(w http.ResponseWriter, r *http.Request, userID, serverID uuid.UUID) bool {
+	_, err := h.DB.GetActiveBan(r.Context(), database.GetActiveBanParams{
+		ServerID: serverID,
+		UserID:   userID,
+		ExpiresAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err == nil {
+		respondWithError(w, http.StatusForbidden, "You are banned from this server")
+		return true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check bans")
+		return true
+	}
+
+	return false
+}
+
+func moderationReason(w http.ResponseWriter, reason string) (string, bool) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxModerationReasonLength {
+		respondWithError(w, http.StatusBadRequest, "Reason is too long")
+		return "", false
+	}
+	return reason, true
+}
+
+func simpleBan(ban database.ServerBan) SimpleBan {
+	simple := SimpleBan{
+		ServerID:  ban.ServerID,
+		UserID:    ban.UserID,
+		Reason:    ban.Reason.String,
+		CreatedAt: ban.CreatedAt,
+	}
+	if ban.ExpiresAt.Valid {
+		simple.ExpiresAt = &ban.ExpiresAt.Time
+	}
+	return simple
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/oidc"
+)
+
+const (
+	oauthStatePrefix = "oidc"
+	oauthStateExpiry = 10 * time.Minute
+)
+
+var errEmailNotVerified = errors.New("provider did not return a verified email")
+
+type oauthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+func (h *Handlers) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.OIDC[r.PathValue("provider")]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown provider")
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting login")
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting login")
+		return
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting login")
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		log.Printf("Failed to build %s authorization URL: %v", provider.Name(), err)
+		respondWithError(w, http.StatusBadGateway, "Provider unavailable")
+		return
+	}
+
+	stored := oauthState{
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}
+
+	err = h.RDB.SetJson(oauthStatePrefix+state, stored, oauthStateExpiry)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting login")
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *Handlers) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.OIDC[r.PathValue("provider")]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown provider")
+		return
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.redirectToLogin(w, r, providerErr)
+		return
+	}
+
+	var stored oauthState
+	err := h.RDB.GetDelJSON(oauthStatePrefix+query.Get("state"), &stored)
+	if err != nil || stored.Provider != provider.Name() {
+		h.redirectToLogin(w, r, "invalid_state")
+		return
+	}
+
+	token, err := provider.Exchange(r.Context(), query.Get("code"), stored.CodeVerifier)
+	if err != nil {
+		log.Printf("Failed to exchange %s authorization code: %v", provider.Name(), err)
+		h.redirectToLogin(w, r, "exchange_failed")
+		return
+	}
+
+	claims, err := provider.VerifyIDToken(r.Context(), token.IDToken, stored.Nonce)
+	if err != nil {
+		log.Printf("Failed to verify %s id token: %v", provider.Name(), err)
+		h.redirectToLogin(w, r, "invalid_id_token")
+		return
+	}
+
+	user, err := h.userForIdentity(r.Context(), provider.Name(), claims)
+	if err != nil {
+		if errors.Is(err, errEmailNotVerified) {
+			h.redirectToLogin(w, r, "email_not_verified")
+		} else {
+			log.Printf("Failed to resolve %s identity %s: %v", provider.Name(), claims.Subject, err)
+			h.redirectToLogin(w, r, "login_failed")
+		}
+		return
+	}
+
+	enabled, err := h.twoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		h.redirectToLogin(w, r, "login_failed")
+		return
+	}
+
+	if enabled {
+		challenge, err := h.issueTwoFactorChallenge(user.ID)
+		if err != nil {
+			h.redirectToLogin(w, r, "login_failed")
+			return
+		}
+
+		http.Redirect(w, r, h.AppURL+"/login/2fa?challenge="+url.QueryEscape(challenge), http.StatusFound)
+		return
+	}
+
+	err = h.startSession(w, r, user.ID)
+	if err != nil {
+		h.redirectToLogin(w, r, "login_failed")
+		return
+	}
+
+	http.Redirect(w, r, h.AppURL, http.StatusFound)
+}
+
+// userForIdentity returns the user linked to a provider subject. Unknown
+// subjects are linked to the account with the same email, or to a new
+// password-less account, but only when the provider vouches for the email.
+func (h *Handlers) userForIdentity(ctx context.Context, provider string, claims *oidc.IDTokenClaims) (database.User, error) {
+	now := time.Now().UTC()
+	email := sql.NullString{
+		String: claims.Email,
+		Valid:  claims.Email != "",
+	}
+
+	identity, err := h.DB.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		err = h.DB.TouchUserIdentity(ctx, database.TouchUserIdentityParams{
+			ID:          identity.ID,
+			LastLoginAt: now,
+			Email:       email,
+		})
+		if err != nil {
+			log.Printf("Failed to update identity last login: %v", err)
+		}
+		return h.DB.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return database.User{}, errEmailNotVerified
+	}
+
+	// Linking and any account changes it needs commit together, so a failed
+	// link never leaves a claimed or half-created account behind.
+	var user database.User
+	claimed := false
+	err = h.DB.RunInTx(ctx, func(db DBInterface) error {
+		var err error
+		user, err = db.GetUserByEmail(ctx, claims.Email)
+		switch {
+		case err == nil:
+			claimed = !user.IsVerified.Bool
+			user, err = h.claimUnverifiedUser(ctx, db, user)
+		case errors.Is(err, sql.ErrNoRows):
+			user, err = h.createOAuthUser(ctx, db, claims)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = db.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			ID:          uuid.New(),
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     claims.Subject,
+			Email:       email,
+			CreatedAt:   now,
+			LastLoginAt: now,
+		})
+		return err
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if claimed {
+		err = h.revokeUserSessions(ctx, user.ID, uuid.Nil)
+		if err != nil {
+			log.Printf("Failed to revoke sessions of claimed user: %v", err)
+		}
+
+		err = h.RDB.Delete("user" + user.ID.String())
+		if err != nil {
+			log.Printf("Failed to delete user from cache: %v", err)
+		}
+	}
+
+	return user, nil
+}
+
+// claimUnverifiedUser hands an account whose email was never verified to the
+// person who just proved ownership of that email through a provider. Anyone
+// could have registered the address, so the password they set is cleared;
+// the caller revokes their sessions once the claim commits.
+func (h *Handlers) claimUnverifiedUser(ctx context.Context, db DBInterface, user database.User) (database.User, error) {
+	if user.IsVerified.Bool {
+		return user, nil
+	}
+
+	_, err := db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:        user.ID,
+		Password:  sql.NullString{},
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return db.SetUserVerified(ctx, database.SetUserVerifiedParams{
+		ID:        user.ID,
+		UpdatedAt: time.Now().UTC(),
+		Email:     user.Email,
+	})
+}
+
+func (h *Handlers) createOAuthUser(ctx context.Context, db DBInterface, claims *oidc.IDTokenClaims) (database.User, error) {
+	handle := claims.PreferredUsername
+	if handle == "" {
+		handle = claims.Name
+	}
+	if handle == "" {
+		handle, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user, err := db.CreateUserStandard(ctx, database.CreateUserStandardParams{
+		ID:        uuid.New(),
+		Email:     claims.Email,
+		Handle:    handle,
+		Password:  sql.NullString{},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	roleID, err := db.GetRoleIDByName(ctx, "member")
+	if err != nil {
+		return database.User{}, err
+	}
+
+	_, err = db.CreateUserRoles(ctx, database.CreateUserRolesParams{
+		UserID: user.ID,
+		RoleID: roleID,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return db.SetUserVerified(ctx, database.SetUserVerifiedParams{
+		ID:        user.ID,
+		UpdatedAt: time.Now().UTC(),
+		Email:     user.Email,
+	})
+}
+
+func (h *Handlers) redirectToLogin(w http.ResponseWriter, r *http.Request, reason string) {
+	http.Redirect(w, r, h.AppURL+"/login?error="+url.QueryEscape(reason), http.StatusFound)
+}
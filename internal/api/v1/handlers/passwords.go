@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTokenExpiry = time.Hour
+	minPasswordLength        = 8
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (h *Handlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	request := ForgotPasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// Respond the same way whether or not the account exists so the endpoint
+	// cannot be used to discover registered emails.
+	user, err := h.DB.GetUserByEmail(r.Context(), request.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error getting user by email: %v", err)
+		}
+		respondNoBody(w, http.StatusAccepted)
+		return
+	}
+
+	err = h.sendPasswordResetEmail(r.Context(), user)
+	if err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+	}
+
+	respondNoBody(w, http.StatusAccepted)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (h *Handlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	request := ResetPasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if len(request.Password) < minPasswordLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Password must be at least %d characters", minPasswordLength))
+		return
+	}
+
+	now := time.Now().UTC()
+
+	// The token is only spent if the new password is stored with it.
+	var token database.PasswordResetToken
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		token, err = db.UsePasswordResetToken(r.Context(), database.UsePasswordResetTokenParams{
+			TokenHash: utils.HashToken(request.Token),
+			UsedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		err = h.setPassword(r.Context(), db, token.UserID, request.Password)
+		if err != nil {
+			return err
+		}
+
+		return db.InvalidateUserPasswordResetTokens(r.Context(), database.InvalidateUserPasswordResetTokensParams{
+			UserID: token.UserID,
+			UsedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to reset password")
+		}
+		return
+	}
+
+	h.passwordChanged(r.Context(), token.UserID, uuid.Nil)
+
+	respondNoBody(w, http.StatusOK)
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (h *Handlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	request := ChangePasswordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if len(request.NewPassword) < minPasswordLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Password must be at least %d characters", minPasswordLength))
+		return
+	}
+
+	// The cached user may be stale, so compare against the stored hash.
+	current, err := h.DB.GetUserByEmail(r.Context(), user.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+
+	if !current.Password.Valid {
+		respondWithError(w, http.StatusBadRequest, "Account has no password, use password reset instead")
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(current.Password.String), []byte(request.CurrentPassword))
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Current password incorrect")
+		return
+	}
+
+	sessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+
+	err = h.setPassword(r.Context(), h.DB, user.ID, request.NewPassword)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to change password")
+		return
+	}
+
+	h.passwordChanged(r.Context(), user.ID, sessionID)
+
+	respondNoBody(w, http.StatusOK)
+}
+
+// setPassword stores a new bcrypt hash for the user.
+func (h *Handlers) setPassword(ctx context.Context, db DBInterface, userID uuid.UUID, password string) error {
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID: userID,
+		Password: sql.NullString{
+			String: string(hashedPasswordBytes),
+			Valid:  true,
+		},
+		UpdatedAt: time.Now().UTC(),
+	})
+	return err
+}
+
+// passwordChanged signs out every session except keep and drops the cached
+// user record once a new password is stored.
+func (h *Handlers) passwordChanged(ctx context.Context, userID, keep uuid.UUID) {
+	err := h.revokeUserSessions(ctx, userID, keep)
+	if err != nil {
+		log.Printf("Failed to revoke sessions after password change: %v", err)
+	}
+
+	err = h.RDB.Delete("user" + userID.String())
+	if err != nil {
+		log.Printf("Failed to delete user from cache: %v", err)
+	}
+}
+
+func (h *Handlers) sendPasswordResetEmail(ctx context.Context, user database.User) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	_, err = h.DB.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: utils.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(passwordResetTokenExpiry),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GleamSpeak password",
+		Body: fmt.Sprintf("Hi %s,\n\nReset your password by opening the link below:\n\n%s/reset-password?token=%s\n\nThe link expires in 1 hour. If you did not request a reset you can ignore this email.",
+			user.Handle, h.AppURL, token),
+	})
+}
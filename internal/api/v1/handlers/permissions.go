@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+)
+
+// HasPermission reports whether the user holds perm in the server.
+func (h *Handlers) HasPermission(ctx context.Context, userID, serverID uuid.UUID, perm permissions.Permission) (bool, error) {
+	return permissions.HasPermission(ctx, h.DB, userID, serverID, perm)
+}
+
+// serverMember resolves the caller's standing in a server, responding 403
+// when they are not a member.
+func (h *Handlers) serverMember(w http.ResponseWriter, r *http.Request, userID, serverID uuid.UUID) (permissions.Member, bool) {
+	member, err := permissions.GetMember(r.Context(), h.DB, userID, serverID)
+	if err != nil {
+		if errors.Is(err, permissions.ErrNotMember) {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+		} else {
+			log.Printf("Failed to load permissions for %s in %s: %v", userID, serverID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to check permissions")
+		}
+		return permissions.Member{}, false
+	}
+
+	return member, true
+}
+
+// authorize is the policy check every server mutation goes through. It
+// resolves the caller's membership and responds 403 unless they are a member
+// holding perm.
+func (h *Handlers) authorize(w http.ResponseWriter, r *http.Request, userID, serverID uuid.UUID, perm permissions.Permission) (permissions.Member, bool) {
+	member, ok := h.serverMember(w, r, userID, serverID)
+	if !ok {
+		return permissions.Member{}, false
+	}
+
+	if !member.Has(perm) {
+		respondWithError(w, http.StatusForbidden, "Forbidden")
+		return permissions.Member{}, false
+	}
+
+	return member, true
+}
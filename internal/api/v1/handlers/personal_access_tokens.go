@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const maxPersonalAccessTokenDays = 365
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (h *Handlers) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	request := CreatePersonalAccessTokenRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if request.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	if len(request.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !common.IsValidScope(scope) {
+			respondWithError(w, http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+
+	if request.ExpiresInDays < 0 || request.ExpiresInDays > maxPersonalAccessTokenDays {
+		respondWithError(w, http.StatusBadRequest, "Invalid expiry")
+		return
+	}
+
+	token, err := utils.GeneratePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	// Zero means the token never expires.
+	expiresAt := sql.NullTime{}
+	if request.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{
+			Time:  now.AddDate(0, 0, request.ExpiresInDays),
+			Valid: true,
+		}
+	}
+
+	pat, err := h.DB.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      request.Name,
+		TokenHash: utils.HashToken(token),
+		Scopes:    request.Scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		return
+	}
+
+	response := CreatePersonalAccessTokenResponse{
+		SimplePersonalAccessToken: simplePersonalAccessToken(pat),
+		Token:                     token,
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (h *Handlers) GetPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokens, err := h.DB.GetUserPersonalAccessTokens(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch tokens")
+		return
+	}
+
+	simpleTokens := make([]SimplePersonalAccessToken, len(tokens))
+	for i, token := range tokens {
+		simpleTokens[i] = simplePersonalAccessToken(token)
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleTokens)
+}
+
+func (h *Handlers) DeletePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	deleted, err := h.DB.DeletePersonalAccessToken(r.Context(), database.DeletePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete token")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+func simplePersonalAccessToken(token database.PersonalAccessToken) SimplePersonalAccessToken {
+	simple := SimplePersonalAccessToken{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if token.ExpiresAt.Valid {
+		simple.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		simple.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return simple
+}
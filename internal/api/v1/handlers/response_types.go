@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -53,6 +54,9 @@ type SimpleServer struct {
 	MemberCount     int32     `json:"member_count"`
 	ServerLevel     int32     `json:"server_level"`
 	MaxMembers      int32     `json:"max_members"`
+	BoostCount      int32     `json:"boost_count"`
+	UploadLimit     int64     `json:"upload_limit"`
+	EmojiSlots      int32     `json:"emoji_slots"`
 	ServerCreatedAt time.Time `json:"server_created_at"`
 	ServerUpdatedAt time.Time `json:"server_updated_at"`
 }
@@ -70,6 +74,16 @@ type SimpleRecentServer struct {
 	OwnerAvatar     string    `json:"owner_avatar"`
 }
 
+type SimpleDiscoveredServer struct {
+	SimpleRecentServer
+	Tags []string `json:"tags"`
+}
+
+type DiscoverServersResponse struct {
+	Servers    []SimpleDiscoveredServer `json:"servers"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
 type SimpleDisplayServerResponse struct {
 	UserID  uuid.UUID      `json:"user_id"`
 	Servers []SimpleServer `json:"servers"`
@@ -120,18 +134,169 @@ type GetServerVoiceChannelResponse struct {
 }
 
 type SimpleMessage struct {
-	ID          uuid.UUID `json:"id"`
-	OwnerID     uuid.UUID `json:"owner_id"`
-	OwnerHandle string    `json:"handle"`
-	OwnerImage  string    `json:"owner_image"`
-	ChannelID   uuid.UUID `json:"channel_id"`
-	Message     string    `json:"message"`
-	Image       string    `json:"image"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	OwnerHandle string     `json:"handle"`
+	OwnerImage  string     `json:"owner_image"`
+	IsBot       bool       `json:"is_bot"`
+	ChannelID   uuid.UUID  `json:"channel_id"`
+	Message     string     `json:"message"`
+	Image       string     `json:"image"`
+	WebhookID   *uuid.UUID `json:"webhook_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type SignedURLResponse struct {
 	URL       string `json:"url"`
 	PublicURL string `json:"public_url"`
 }
+
+type WebSocketTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type SimpleSession struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+type SimplePersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	SimplePersonalAccessToken
+	Token string `json:"token"`
+}
+
+type SimpleBot struct {
+	ID        uuid.UUID `json:"id"`
+	Handle    string    `json:"handle"`
+	Avatar    string    `json:"avatar"`
+	OwnerID   uuid.UUID `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BotTokenResponse struct {
+	Bot   SimpleBot `json:"bot"`
+	Token string    `json:"token"`
+}
+
+type SimpleRole struct {
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	Name        string    `json:"name"`
+	Permissions int64     `json:"permissions"`
+	Position    int32     `json:"position"`
+	IsDefault   bool      `json:"is_default"`
+}
+
+type SimpleInvite struct {
+	Code      string     `json:"code"`
+	ServerID  uuid.UUID  `json:"server_id"`
+	CreatorID *uuid.UUID `json:"creator_id"`
+	MaxUses   *int32     `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SimpleJoinRequest struct {
+	ID        uuid.UUID  `json:"id"`
+	ServerID  uuid.UUID  `json:"server_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Handle    string     `json:"handle,omitempty"`
+	Avatar    string     `json:"avatar,omitempty"`
+	Message   string     `json:"message"`
+	Status    string     `json:"status"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SimpleBan struct {
+	ServerID  uuid.UUID  `json:"server_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Handle    string     `json:"handle,omitempty"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type SimpleMember struct {
+	UserID     uuid.UUID   `json:"user_id"`
+	Handle     string      `json:"handle"`
+	Avatar     string      `json:"avatar"`
+	IsBot      bool        `json:"is_bot"`
+	IsOwner    bool        `json:"is_owner"`
+	Online     bool        `json:"online"`
+	RoleIDs    []uuid.UUID `json:"role_ids"`
+	MutedUntil *time.Time  `json:"muted_until,omitempty"`
+}
+
+type ServerMembersResponse struct {
+	ServerID   uuid.UUID      `json:"server_id"`
+	Members    []SimpleMember `json:"members"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type AuditLogEntry struct {
+	ID          uuid.UUID       `json:"id"`
+	ActorID     *uuid.UUID      `json:"actor_id"`
+	ActorHandle string          `json:"actor_handle,omitempty"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"target_type"`
+	TargetID    *uuid.UUID      `json:"target_id"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	Reason      string          `json:"reason,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type AuditLogResponse struct {
+	ServerID   uuid.UUID       `json:"server_id"`
+	Entries    []AuditLogEntry `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type SimpleWebhook struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	CreatorID uuid.UUID `json:"creator_id"`
+	Name      string    `json:"name"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateWebhookResponse struct {
+	SimpleWebhook
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+)
+
+var (
+	errRoleNotFound       = errors.New("role not found")
+	errMemberRoleNotFound = errors.New("member does not have role")
+)
+
+type RoleRequest struct {
+	Name        string `json:"name"`
+	Permissions int64  `json:"permissions"`
+	Position    int32  `json:"position"`
+}
+
+func (h *Handlers) GetServerRoles(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	if _, ok := h.serverMember(w, r, user.ID, serverID); !ok {
+		return
+	}
+
+	roles, err := h.DB.GetServerRoles(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleRoles(roles))
+}
+
+func (h *Handlers) CreateServerRole(w http.ResponseWriter, r *http.Request) {
+	member, ok := h.roleManager(w, r)
+	if !ok {
+		return
+	}
+
+	request := RoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if !validateRoleRequest(w, member, &request) {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	var role database.ServerRole
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		role, err = db.CreateServerRole(r.Context(), database.CreateServerRoleParams{
+			ID:          uuid.New(),
+			ServerID:    member.ServerID,
+			Name:        request.Name,
+			Permissions: request.Permissions,
+			Position:    request.Position,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   role.ServerID,
+			ActorID:    member.UserID,
+			Action:     auditRoleCreate,
+			TargetType: auditTargetRole,
+			TargetID:   role.ID,
+			After:      simpleRole(role),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, simpleRole(role))
+}
+
+func (h *Handlers) UpdateServerRole(w http.ResponseWriter, r *http.Request) {
+	member, ok := h.roleManager(w, r)
+	if !ok {
+		return
+	}
+
+	role, ok := h.managedRole(w, r, member)
+	if !ok {
+		return
+	}
+
+	request := RoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	// The default role always sits at the bottom of the hierarchy.
+	if role.IsDefault {
+		request.Position = 0
+		if strings.TrimSpace(request.Name) == "" {
+			request.Name = role.Name
+		}
+	}
+
+	if !validateRoleRequest(w, member, &request) {
+		return
+	}
+
+	var updated database.ServerRole
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		updated, err = db.UpdateServerRole(r.Context(), database.UpdateServerRoleParams{
+			ID:          role.ID,
+			ServerID:    role.ServerID,
+			Name:        request.Name,
+			Permissions: request.Permissions,
+			Position:    request.Position,
+			UpdatedAt:   time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   role.ServerID,
+			ActorID:    member.UserID,
+			Action:     auditRoleUpdate,
+			TargetType: auditTargetRole,
+			TargetID:   role.ID,
+			Before:     simpleRole(role),
+			After:      simpleRole(updated),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update role")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleRole(updated))
+}
+
+func (h *Handlers) DeleteServerRole(w http.ResponseWriter, r *http.Request) {
+	member, ok := h.roleManager(w, r)
+	if !ok {
+		return
+	}
+
+	role, ok := h.managedRole(w, r, member)
+	if !ok {
+		return
+	}
+
+	if role.IsDefault {
+		respondWithError(w, http.StatusBadRequest, "The default role cannot be deleted")
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		deleted, err := db.DeleteServerRole(r.Context(), database.DeleteServerRoleParams{
+			ID:       role.ID,
+			ServerID: role.ServerID,
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return errRoleNotFound
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   role.ServerID,
+			ActorID:    member.UserID,
+			Action:     auditRoleDelete,
+			TargetType: auditTargetRole,
+			TargetID:   role.ID,
+			Before:     simpleRole(role),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errRoleNotFound) {
+			respondWithError(w, http.StatusNotFound, "Role not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete role")
+		}
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+func (h *Handlers) GetMemberRoles(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if _, ok := h.serverMember(w, r, user.ID, serverID); !ok {
+		return
+	}
+
+	roles, err := h.DB.GetMemberRoles(r.Context(), database.GetMemberRolesParams{
+		UserID:   memberID,
+		ServerID: serverID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleRoles(roles))
+}
+
+func (h *Handlers) AddMemberRole(w http.ResponseWriter, r *http.Request) {
+	member, memberID, role, ok := h.memberRoleTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := permissions.GetMember(r.Context(), h.DB, memberID, member.ServerID); err != nil {
+		if errors.Is(err, permissions.ErrNotMember) {
+			respondWithError(w, http.StatusNotFound, "Member not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to assign role")
+		}
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		err := db.AddMemberRole(r.Context(), database.AddMemberRoleParams{
+			UserID:   memberID,
+			ServerID: member.ServerID,
+			RoleID:   role.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   member.ServerID,
+			ActorID:    member.UserID,
+			Action:     auditMemberRoleAdd,
+			TargetType: auditTargetMember,
+			TargetID:   memberID,
+			After:      memberRoleDetails(role),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to assign role")
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+func (h *Handlers) RemoveMemberRole(w http.ResponseWriter, r *http.Request) {
+	member, memberID, role, ok := h.memberRoleTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		removed, err := db.RemoveMemberRole(r.Context(), database.RemoveMemberRoleParams{
+			UserID:   memberID,
+			ServerID: member.ServerID,
+			RoleID:   role.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return errMemberRoleNotFound
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   member.ServerID,
+			ActorID:    member.UserID,
+			Action:     auditMemberRoleRemove,
+			TargetType: auditTargetMember,
+			TargetID:   memberID,
+			Before:     memberRoleDetails(role),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errMemberRoleNotFound) {
+			respondWithError(w, http.StatusNotFound, "Member does not have this role")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to remove role")
+		}
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+// roleManager resolves the caller in the server named in the path and checks
+// that they may manage its roles.
+func (h *Handlers) roleManager(w http.ResponseWriter, r *http.Request) (permissions.Member, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return permissions.Member{}, false
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return permissions.Member{}, false
+	}
+
+	return h.authorize(w, r, user.ID, serverID, permissions.ManageRoles)
+}
+
+// managedRole loads the role named in the path and checks that it sits below
+// the caller's highest role.
+func (h *Handlers) managedRole(w http.ResponseWriter, r *http.Request, member permissions.Member) (database.ServerRole, bool) {
+	roleID, err := uuid.Parse(r.PathValue("roleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid role ID")
+		return database.ServerRole{}, false
+	}
+
+	role, err := h.DB.GetServerRole(r.Context(), database.GetServerRoleParams{
+		ID:       roleID,
+		ServerID: member.ServerID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Role not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch role")
+		}
+		return database.ServerRole{}, false
+	}
+
+	if !member.Outranks(role.Position) {
+		respondWithError(w, http.StatusForbidden, "Role is above your highest role")
+		return database.ServerRole{}, false
+	}
+
+	return role, true
+}
+
+// memberRoleTarget resolves the caller, the member and the role for the
+// member role assignment routes.
+func (h *Handlers) memberRoleTarget(w http.ResponseWriter, r *http.Request) (permissions.Member, uuid.UUID, database.ServerRole, bool) {
+	member, ok := h.roleManager(w, r)
+	if !ok {
+		return permissions.Member{}, uuid.Nil, database.ServerRole{}, false
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return permissions.Member{}, uuid.Nil, database.ServerRole{}, false
+	}
+
+	role, ok := h.managedRole(w, r, member)
+	if !ok {
+		return permissions.Member{}, uuid.Nil, database.ServerRole{}, false
+	}
+
+	// Every member holds the default role implicitly.
+	if role.IsDefault {
+		respondWithError(w, http.StatusBadRequest, "The default role cannot be assigned")
+		return permissions.Member{}, uuid.Nil, database.ServerRole{}, false
+	}
+
+	return member, memberID, role, true
+}
+
+// validateRoleRequest checks a role against the caller's own standing: it must
+// sit below their highest role and may only grant permissions they hold.
+func validateRoleRequest(w http.ResponseWriter, member permissions.Member, request *RoleRequest) bool {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Role name is required")
+		return false
+	}
+
+	perms := permissions.Permission(request.Permissions)
+	if !perms.Valid() {
+		respondWithError(w, http.StatusBadRequest, "Unknown permissions")
+		return false
+	}
+
+	if request.Position < 0 {
+		respondWithError(w, http.StatusBadRequest, "Position cannot be negative")
+		return false
+	}
+
+	if !member.Outranks(request.Position) {
+		respondWithError(w, http.StatusForbidden, "Role must be below your highest role")
+		return false
+	}
+
+	if !member.IsOwner && !member.Permissions.Has(permissions.Administrator) && perms&^member.Permissions != 0 {
+		respondWithError(w, http.StatusForbidden, "Cannot grant permissions you do not have")
+		return false
+	}
+
+	return true
+}
+
+// memberRoleDetails is a role assignment as recorded in the audit log.
+func memberRoleDetails(role database.ServerRole) map[string]any {
+	return map[string]any{
+		"role_id":   role.ID,
+		"role_name": role.Name,
+	}
+}
+
+func simpleRole(role database.ServerRole) SimpleRole {
+	return SimpleRole{
+		ID:          role.ID,
+		ServerID:    role.ServerID,
+		Name:        role.Name,
+		Permissions: role.Permissions,
+		Position:    role.Position,
+		IsDefault:   role.IsDefault,
+	}
+}
+
+func simpleRoles(roles []database.ServerRole) []SimpleRole {
+	simple := make([]SimpleRole, len(roles))
+	for i, role := range roles {
+		simple[i] = simpleRole(role)
+	}
+	return simple
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,6 +13,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/levels"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
 )
 
 type CreateServerRequest struct {
@@ -33,30 +38,63 @@ func (h *Handlers) CreateServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	inviteCode, err := utils.GenerateInviteCode(inviteCodeLength)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create server")
+		return
+	}
+
 	serverParams := database.CreateServerParams{
 		ID:         uuid.New(),
 		OwnerID:    user.ID,
 		ServerName: request.ServerName,
-		InviteCode: uuid.New().String()[:12],
+		InviteCode: inviteCode,
 		CreatedAt:  time.Now().UTC(),
 		UpdatedAt:  time.Now().UTC(),
 	}
 
-	server, err := h.DB.CreateServer(r.Context(), serverParams)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create server")
-		return
-	}
+	// The server, its owner's membership, default role and invite are
+	// created together so a failure never leaves a server nobody can use.
+	var server database.Server
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		server, err = db.CreateServer(r.Context(), serverParams)
+		if err != nil {
+			return err
+		}
 
-	userServerParams := database.CreateUserServerParams{
-		UserID:   user.ID,
-		ServerID: server.ID,
-		Role:     serverAdmin,
-	}
+		_, err = db.CreateUserServer(r.Context(), database.CreateUserServerParams{
+			UserID:   user.ID,
+			ServerID: server.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = db.CreateServerRole(r.Context(), database.CreateServerRoleParams{
+			ID:          uuid.New(),
+			ServerID:    server.ID,
+			Name:        defaultRoleName,
+			Permissions: int64(permissions.Default),
+			IsDefault:   true,
+			CreatedAt:   server.CreatedAt,
+			UpdatedAt:   server.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
 
-	_, err = h.DB.CreateUserServer(r.Context(), userServerParams)
+		// The server's own invite code is a permanent invite from the owner.
+		_, err = db.CreateInvite(r.Context(), database.CreateInviteParams{
+			Code:      server.InviteCode,
+			ServerID:  server.ID,
+			CreatorID: uuid.NullUUID{UUID: user.ID, Valid: true},
+			CreatedAt: server.CreatedAt,
+		})
+		return err
+	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to join server")
+		respondWithError(w, http.StatusInternalServerError, "Failed to create server")
 		return
 	}
 
@@ -107,6 +145,90 @@ func (h *Handlers) DeleteServer(w http.ResponseWriter, r *http.Request) {
 	respondNoBody(w, http.StatusOK)
 }
 
+type TransferServerRequest struct {
+	NewOwnerID   uuid.UUID `json:"new_owner_id"`
+	Password     string    `json:"password"`
+	Code         string    `json:"code"`
+	RecoveryCode string    `json:"recovery_code"`
+}
+
+// TransferServerOwnership hands a server to another member. The owner must
+// confirm with their password or a second factor.
+func (h *Handlers) TransferServerOwnership(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	serverID, err := uuid.Parse(r.PathValue("serverID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid server ID")
+		return
+	}
+
+	request := TransferServerRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	server, err := h.DB.GetOneServerByID(r.Context(), serverID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if server.OwnerID != user.ID {
+		respondWithError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	if request.NewOwnerID == user.ID {
+		respondWithError(w, http.StatusBadRequest, "You already own this server")
+		return
+	}
+
+	if !h.confirmIdentity(w, r, user, request.Password, request.Code, request.RecoveryCode) {
+		return
+	}
+
+	newOwner, err := h.DB.GetUserByID(r.Context(), request.NewOwnerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if newOwner.IsBot {
+		respondWithError(w, http.StatusBadRequest, "Bots cannot own servers")
+		return
+	}
+
+	transferred, err := h.DB.TransferServerOwnership(r.Context(), database.TransferServerOwnershipParams{
+		NewOwnerID: newOwner.ID,
+		UpdatedAt:  time.Now().UTC(),
+		ID:         serverID,
+		OwnerID:    user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "New owner must be a member of the server")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to transfer ownership")
+		}
+		return
+	}
+
+	response := simpleServer(database.Server(transferred))
+
+	err = h.Ws.SendToServer(r.Context(), serverID, websocket.EventServerUpdated, response)
+	if err != nil {
+		log.Printf("Failed to broadcast ownership transfer of %s: %v", serverID, err)
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
 func (h *Handlers) GetServerByID(w http.ResponseWriter, r *http.Request) {
 	serverID := strings.TrimPrefix(r.URL.Path, "/v1/servers/")
 
@@ -122,23 +244,7 @@ func (h *Handlers) GetServerByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := SimpleServer{
-		ServerID:        server.ID,
-		OwnerID:         server.OwnerID,
-		ServerName:      server.ServerName,
-		Description:     server.Description.String,
-		IconURL:         server.IconUrl.String,
-		BannerURL:       server.BannerUrl.String,
-		IsPublic:        server.IsPublic.Bool,
-		InviteCode:      server.InviteCode,
-		MemberCount:     server.MemberCount.Int32,
-		ServerLevel:     server.ServerLevel.Int32,
-		MaxMembers:      server.MaxMembers.Int32,
-		ServerCreatedAt: server.CreatedAt,
-		ServerUpdatedAt: server.UpdatedAt,
-	}
-
-	respondWithJSON(w, http.StatusOK, response)
+	respondWithJSON(w, http.StatusOK, simpleServer(server))
 
 }
 
@@ -169,6 +275,9 @@ func (h *Handlers) GetUserServers(w http.ResponseWriter, r *http.Request) {
 			MemberCount:     server.MemberCount.Int32,
 			ServerLevel:     server.ServerLevel.Int32,
 			MaxMembers:      server.MaxMembers.Int32,
+			BoostCount:      server.BoostCount,
+			UploadLimit:     levels.Get(server.ServerLevel.Int32).UploadLimit,
+			EmojiSlots:      levels.Get(server.ServerLevel.Int32).EmojiSlots,
 			ServerCreatedAt: server.ServerCreatedAt,
 			ServerUpdatedAt: server.ServerUpdatedAt,
 		}
@@ -199,48 +308,80 @@ func (h *Handlers) JoinServerByCode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	foundServer, err := h.DB.GetOneServerByCode(r.Context(), request.InviteCode)
+	invite, err := h.DB.GetInviteByCode(r.Context(), request.InviteCode)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Invite not found")
+		return
+	}
+
+	if !inviteUsable(invite, time.Now().UTC()) {
+		respondWithError(w, http.StatusGone, "Invite is no longer valid")
+		return
+	}
+
+	foundServer, err := h.DB.GetOneServerByID(r.Context(), invite.ServerID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Server not found")
 		return
 	}
 
-	userServerParams := database.CreateUserServerParams{
-		UserID:   user.ID,
-		ServerID: foundServer.ID,
-		Role:     serverUser,
+	if !foundServer.IsPublic.Bool {
+		respondWithError(w, http.StatusForbidden, "Server is private, submit a join request")
+		return
 	}
 
-	var userServer database.UserServer
-	if foundServer.IsPublic.Bool {
-		userServer, err = h.DB.CreateUserServer(r.Context(), userServerParams)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to join server")
-			return
-		}
-	} else {
-		respondWithError(w, http.StatusForbidden, "Server is not public")
+	if h.rejectBanned(w, r, user.ID, foundServer.ID) {
 		return
 	}
 
-	newCount := sql.NullInt32{
-		Int32: foundServer.MemberCount.Int32 + 1,
-		Valid: true,
+	_, err = h.DB.GetUserServer(r.Context(), database.GetUserServerParams{
+		UserID:   user.ID,
+		ServerID: foundServer.ID,
+	})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "Already a member of this server")
+		return
 	}
 
-	updateMemberCountParams := database.UpdateServerMemberCountParams{
-		ID:          foundServer.ID,
-		MemberCount: newCount,
+	if serverFull(foundServer) {
+		respondWithError(w, http.StatusForbidden, "Server is full")
+		return
 	}
 
-	_, err = h.DB.UpdateServerMemberCount(r.Context(), updateMemberCountParams)
+	// Claiming the use, counting the member and adding them happen in one
+	// statement, so concurrent joins cannot push the invite past max_uses or
+	// the server past max_members.
+	userServer, err := h.DB.JoinServerWithInvite(r.Context(), database.JoinServerWithInviteParams{
+		Code:   invite.Code,
+		UserID: user.ID,
+		ExpiresAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
 	if err != nil {
-		log.Printf("Failed to update member count: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			h.respondJoinRejected(w, r, foundServer.ID)
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to join server")
+		}
+		return
 	}
 
 	respondWithJSON(w, http.StatusCreated, userServer)
 }
 
+// respondJoinRejected explains why an invite join matched no rows: either
+// the server filled up or the invite stopped being usable.
+func (h *Handlers) respondJoinRejected(w http.ResponseWriter, r *http.Request, serverID uuid.UUID) {
+	server, err := h.DB.GetOneServerByID(r.Context(), serverID)
+	if err == nil && serverFull(server) {
+		respondWithError(w, http.StatusForbidden, "Server is full")
+		return
+	}
+	respondWithError(w, http.StatusGone, "Invite is no longer valid")
+}
+
 type JoinServerByIDRequest struct {
 	ServerID uuid.UUID `json:"server_id"`
 }
@@ -264,31 +405,44 @@ func (h *Handlers) JoinServerByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userServerParams := database.CreateUserServerParams{
-		UserID:   user.ID,
-		ServerID: request.ServerID,
-		Role:     serverUser,
+	if !foundServer.IsPublic.Bool {
+		respondWithError(w, http.StatusForbidden, "Server is private, submit a join request")
+		return
 	}
 
-	userServer, err := h.DB.CreateUserServer(r.Context(), userServerParams)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to join server")
+	if h.rejectBanned(w, r, user.ID, foundServer.ID) {
 		return
 	}
 
-	newCount := sql.NullInt32{
-		Int32: foundServer.MemberCount.Int32 + 1,
-		Valid: true,
+	_, err = h.DB.GetUserServer(r.Context(), database.GetUserServerParams{
+		UserID:   user.ID,
+		ServerID: foundServer.ID,
+	})
+	if err == nil {
+		respondWithError(w, http.StatusConflict, "Already a member of this server")
+		return
 	}
 
-	updateMemberCountParams := database.UpdateServerMemberCountParams{
-		ID:          request.ServerID,
-		MemberCount: newCount,
+	if serverFull(foundServer) {
+		respondWithError(w, http.StatusForbidden, "Server is full")
+		return
 	}
 
-	_, err = h.DB.UpdateServerMemberCount(r.Context(), updateMemberCountParams)
+	userServer, err := h.DB.JoinServerMember(r.Context(), database.JoinServerMemberParams{
+		ServerID: request.ServerID,
+		UserID:   user.ID,
+	})
 	if err != nil {
-		log.Printf("Failed to update member count: %v", err)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondWithError(w, http.StatusForbidden, "Server is full")
+		case isUniqueViolation(err, "user_servers_pkey"):
+			// A concurrent join got there first.
+			respondWithError(w, http.StatusConflict, "Already a member of this server")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to join server")
+		}
+		return
 	}
 
 	respondWithJSON(w, http.StatusCreated, userServer)
@@ -319,12 +473,38 @@ func (h *Handlers) LeaveServer(w http.ResponseWriter, r *http.Request) {
 		ServerID: request.ServerID,
 	}
 
-	err = h.DB.DeleteUserServer(r.Context(), userServerParams)
+	server, err := h.DB.GetOneServerByID(r.Context(), request.ServerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if server.OwnerID == user.ID {
+		respondWithError(w, http.StatusConflict, "Transfer ownership before leaving the server")
+		return
+	}
+
+	var boosted database.Server
+	var dropped bool
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		err := db.DeleteUserServer(r.Context(), userServerParams)
+		if err != nil {
+			return err
+		}
+
+		boosted, dropped, err = dropMemberBoost(r.Context(), db, user.ID, request.ServerID)
+		return err
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to leave server")
 		return
 	}
 
+	h.Ws.RemoveServerMember(user.ID, request.ServerID)
+	if dropped {
+		h.notifyBoostChange(r, boosted)
+	}
+
 	respondNoBody(w, http.StatusOK)
 }
 
@@ -363,6 +543,12 @@ type UpdateServerImageRequest struct {
 }
 
 func (h *Handlers) UpdateServerImages(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	request := UpdateServerImageRequest{}
 
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -372,35 +558,62 @@ func (h *Handlers) UpdateServerImages(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("Decoded request: %+v", request)
 
-	if request.IsIcon {
-		params := database.UpdateServerIconByIDParams{
-			ID: request.ServerID,
-			IconUrl: sql.NullString{
-				String: request.URL,
-				Valid:  request.URL != "",
-			},
-		}
-		updatedServer, err := h.DB.UpdateServerIconByID(r.Context(), params)
+	if _, ok := h.authorize(w, r, user.ID, request.ServerID, permissions.ManageServer); !ok {
+		return
+	}
+
+	url := sql.NullString{
+		String: request.URL,
+		Valid:  request.URL != "",
+	}
+
+	var updatedServer any
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		server, err := db.GetOneServerByID(r.Context(), request.ServerID)
 		if err != nil {
-			respondWithError(w, http.StatusNotFound, "Server not found")
-			return
+			return err
 		}
-		respondWithJSON(w, http.StatusOK, updatedServer)
-	} else {
-		params := database.UpdateServerBannerByIDParams{
-			ID: request.ServerID,
-			BannerUrl: sql.NullString{
-				String: request.URL,
-				Valid:  request.URL != "",
-			},
+
+		entry := auditEntry{
+			ServerID:   server.ID,
+			ActorID:    user.ID,
+			TargetType: auditTargetServer,
+			TargetID:   server.ID,
+		}
+
+		if request.IsIcon {
+			updatedServer, err = db.UpdateServerIconByID(r.Context(), database.UpdateServerIconByIDParams{
+				ID:      request.ServerID,
+				IconUrl: url,
+			})
+			entry.Action = auditServerIconUpdate
+			entry.Before = map[string]string{"icon_url": server.IconUrl.String}
+			entry.After = map[string]string{"icon_url": url.String}
+		} else {
+			updatedServer, err = db.UpdateServerBannerByID(r.Context(), database.UpdateServerBannerByIDParams{
+				ID:        request.ServerID,
+				BannerUrl: url,
+			})
+			entry.Action = auditServerBannerUpdate
+			entry.Before = map[string]string{"banner_url": server.BannerUrl.String}
+			entry.After = map[string]string{"banner_url": url.String}
 		}
-		updatedServer, err := h.DB.UpdateServerBannerByID(r.Context(), params)
 		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, entry)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
 			respondWithError(w, http.StatusNotFound, "Server not found")
-			return
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update server")
 		}
-		respondWithJSON(w, http.StatusOK, updatedServer)
+		return
 	}
+
+	respondWithJSON(w, http.StatusOK, updatedServer)
 }
 
 type UpdateServerRequest struct {
@@ -410,6 +623,11 @@ type UpdateServerRequest struct {
 }
 
 func (h *Handlers) UpdateServer(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 
 	request := UpdateServerRequest{}
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -418,6 +636,10 @@ func (h *Handlers) UpdateServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.authorize(w, r, user.ID, request.ServerID, permissions.ManageServer); !ok {
+		return
+	}
+
 	params := database.UpdateServerByIDParams{
 		ServerName: request.ServerName,
 		UpdatedAt:  time.Now().UTC(),
@@ -430,27 +652,70 @@ func (h *Handlers) UpdateServer(w http.ResponseWriter, r *http.Request) {
 			Valid:  true,
 		}
 
-		updatedServer, err := h.DB.UpdateServerByID(r.Context(), params)
+		var updatedServer database.Server
+		err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+			server, err := db.GetOneServerByID(r.Context(), request.ServerID)
+			if err != nil {
+				return err
+			}
+
+			updatedServer, err = db.UpdateServerByID(r.Context(), params)
+			if err != nil {
+				return err
+			}
+
+			return recordAudit(r.Context(), db, auditEntry{
+				ServerID:   server.ID,
+				ActorID:    user.ID,
+				Action:     auditServerUpdate,
+				TargetType: auditTargetServer,
+				TargetID:   server.ID,
+				Before:     serverDetails(server),
+				After:      serverDetails(updatedServer),
+			})
+		})
 		if err != nil {
 			respondWithError(w, http.StatusInternalServerError, "Failed to update user")
 			return
 		}
 
-		response := SimpleServer{
-			ServerID:        updatedServer.ID,
-			OwnerID:         updatedServer.OwnerID,
-			ServerName:      updatedServer.ServerName,
-			Description:     updatedServer.Description.String,
-			IconURL:         updatedServer.IconUrl.String,
-			BannerURL:       updatedServer.BannerUrl.String,
-			IsPublic:        updatedServer.IsPublic.Bool,
-			InviteCode:      updatedServer.InviteCode,
-			MemberCount:     updatedServer.MemberCount.Int32,
-			ServerLevel:     updatedServer.ServerLevel.Int32,
-			ServerCreatedAt: updatedServer.CreatedAt,
-			ServerUpdatedAt: params.UpdatedAt,
-		}
+		response := simpleServer(updatedServer)
 
 		respondWithJSON(w, http.StatusOK, response)
 	}
 }
+
+func simpleServer(server database.Server) SimpleServer {
+	return SimpleServer{
+		ServerID:        server.ID,
+		OwnerID:         server.OwnerID,
+		ServerName:      server.ServerName,
+		Description:     server.Description.String,
+		IconURL:         server.IconUrl.String,
+		BannerURL:       server.BannerUrl.String,
+		IsPublic:        server.IsPublic.Bool,
+		InviteCode:      server.InviteCode,
+		MemberCount:     server.MemberCount.Int32,
+		ServerLevel:     server.ServerLevel.Int32,
+		MaxMembers:      server.MaxMembers.Int32,
+		BoostCount:      server.BoostCount,
+		UploadLimit:     levels.Get(server.ServerLevel.Int32).UploadLimit,
+		EmojiSlots:      levels.Get(server.ServerLevel.Int32).EmojiSlots,
+		ServerCreatedAt: server.CreatedAt,
+		ServerUpdatedAt: server.UpdatedAt,
+	}
+}
+
+// serverDetails is the part of a server UpdateServer can change, as recorded
+// in the audit log.
+func serverDetails(server database.Server) map[string]string {
+	return map[string]string{
+		"server_name": server.ServerName,
+		"description": server.Description.String,
+	}
+}
+
+// serverFull reports whether the server has reached its member cap.
+func serverFull(server database.Server) bool {
+	return server.MaxMembers.Valid && server.MemberCount.Int32 >= server.MaxMembers.Int32
+}
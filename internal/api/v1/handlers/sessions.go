@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+func (h *Handlers) GetSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	currentSessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+
+	sessions, err := h.DB.GetActiveUserSessions(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch sessions")
+		return
+	}
+
+	simpleSessions := make([]SimpleSession, len(sessions))
+	for i, session := range sessions {
+		simpleSessions[i] = SimpleSession{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent.String,
+			IPAddress:  session.IpAddress.String,
+			Current:    session.ID == currentSessionID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleSessions)
+}
+
+func (h *Handlers) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse uuid, possible params error")
+		return
+	}
+
+	err = h.revokeSession(r.Context(), user.ID, sessionUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Session not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+		}
+		return
+	}
+
+	currentSessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+	if sessionUUID == currentSessionID {
+		clearAuthCookies(w)
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+// DeleteOtherSessions signs the user out everywhere except the session the
+// request was made from.
+func (h *Handlers) DeleteOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	currentSessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+
+	err := h.revokeUserSessions(r.Context(), user.ID, currentSessionID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+// revokeSession revokes one of the user's sessions along with its refresh
+// tokens and drops any websocket connections opened from it.
+func (h *Handlers) revokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	_, err := h.DB.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+		RevokedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	h.cleanupRevokedSessions(ctx, sessionID)
+	return nil
+}
+
+// revokeUserSessions revokes every active session of the user except keep.
+// Pass uuid.Nil to revoke all of them.
+func (h *Handlers) revokeUserSessions(ctx context.Context, userID, keep uuid.UUID) error {
+	revoked, err := h.DB.RevokeUserSessionsExcept(ctx, database.RevokeUserSessionsExceptParams{
+		UserID: userID,
+		ID:     keep,
+		RevokedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	h.cleanupRevokedSessions(ctx, revoked...)
+	return nil
+}
+
+func (h *Handlers) cleanupRevokedSessions(ctx context.Context, sessionIDs ...uuid.UUID) {
+	if len(sessionIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		h.revokeRefreshTokenFamily(ctx, sessionID)
+		keys[i] = common.SessionCachePrefix + sessionID.String()
+	}
+
+	if err := h.RDB.Delete(keys...); err != nil {
+		log.Printf("Failed to delete sessions from cache: %v", err)
+	}
+
+	h.Ws.DisconnectSessions(sessionIDs...)
+}
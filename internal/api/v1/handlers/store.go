@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/lib/pq"
+)
+
+// Store is the DBInterface handlers use in production. Its queries run on
+// the connection pool unless it was handed out by RunInTx.
+type Store struct {
+	*database.Queries
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		Queries: database.New(db),
+		db:      db,
+	}
+}
+
+// RunInTx runs fn with a store bound to one transaction, committing if fn
+// returns nil and rolling back otherwise, so a handler that fails partway
+// through a multi-step write leaves nothing behind. Calling RunInTx on a
+// store that is already in a transaction runs fn in that transaction.
+func (s *Store) RunInTx(ctx context.Context, fn func(DBInterface) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(&Store{
+		Queries: s.Queries.WithTx(tx),
+		db:      s.db,
+		tx:      tx,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a row that
+// duplicates one already held by the named unique constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +12,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
 )
 
 type CreateTextChannelRequest struct {
@@ -40,6 +44,10 @@ func (h *Handlers) CreateTextChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.authorize(w, r, user.ID, serverUUID, permissions.ManageChannels); !ok {
+		return
+	}
+
 	languageID, err := h.DB.GetLanguageIDByName(r.Context(), request.Language)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request")
@@ -56,7 +64,22 @@ func (h *Handlers) CreateTextChannel(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   time.Now().UTC(),
 	}
 
-	channel, err := h.DB.CreateTextChannel(r.Context(), channelParams)
+	var channel database.TextChannel
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		channel, err = db.CreateTextChannel(r.Context(), channelParams)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   channel.ServerID,
+			ActorID:    user.ID,
+			Action:     auditChannelCreate,
+			TargetType: auditTargetChannel,
+			TargetID:   channel.ID,
+			After:      channelDetails(channel.ChannelName, channel.LanguageID),
+		})
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create channel")
 		return
@@ -73,7 +96,7 @@ func (h *Handlers) CreateTextChannel(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) DeleteTextChannel(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(common.UserContextKey).(database.User)
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
 	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "Unathorized")
 		return
@@ -87,8 +110,30 @@ func (h *Handlers) DeleteTextChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	channel, err := h.DB.GetTextChannelByID(r.Context(), channelUUID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, channel.ServerID, permissions.ManageChannels); !ok {
+		return
+	}
 
-	err = h.DB.DeleteTextChannel(r.Context(), channelUUID)
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		if err := db.DeleteTextChannel(r.Context(), channelUUID); err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   channel.ServerID,
+			ActorID:    user.ID,
+			Action:     auditChannelDelete,
+			TargetType: auditTargetChannel,
+			TargetID:   channel.ID,
+			Before:     channelDetails(channel.ChannelName, channel.LanguageID),
+		})
+	})
 	if err != nil {
 		respondWithError(w, http.StatusForbidden, "Failed to delete text channel")
 		return
@@ -97,6 +142,14 @@ func (h *Handlers) DeleteTextChannel(w http.ResponseWriter, r *http.Request) {
 	respondNoBody(w, http.StatusOK)
 }
 
+// channelDetails is a text or voice channel as recorded in the audit log.
+func channelDetails(name string, languageID uuid.UUID) map[string]any {
+	return map[string]any{
+		"channel_name": name,
+		"language_id":  languageID,
+	}
+}
+
 func (h *Handlers) GetServerTextChannels(w http.ResponseWriter, r *http.Request) {
 	serverID := strings.TrimPrefix(r.URL.Path, "/v1/channels/")
 
@@ -156,13 +209,103 @@ func (h *Handlers) GetChannelTextMessages(w http.ResponseWriter, r *http.Request
 			OwnerID:     message.OwnerID,
 			OwnerHandle: message.Handle,
 			OwnerImage:  message.AvatarUrl.String,
+			IsBot:       message.IsBot,
 			Message:     message.Message,
 			Image:       message.Image.String,
 			CreatedAt:   message.CreatedAt,
 			UpdatedAt:   message.UpdatedAt,
 		}
 
+		// Webhook messages show the name and avatar they were posted under.
+		// author_name outlives the webhook, whose ID is cleared when it is
+		// deleted.
+		if message.AuthorName.Valid {
+			normalizedMessages[i].OwnerHandle = message.AuthorName.String
+			normalizedMessages[i].OwnerImage = message.AuthorAvatar.String
+			normalizedMessages[i].IsBot = true
+			if message.WebhookID.Valid {
+				normalizedMessages[i].WebhookID = &message.WebhookID.UUID
+			}
+		}
 	}
 
 	respondWithJSON(w, http.StatusOK, normalizedMessages)
 }
+
+type CreateTextMessageRequest struct {
+	Message string `json:"message"`
+	Image   string `json:"image"`
+}
+
+func (h *Handlers) CreateChannelTextMessage(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unathorized")
+		return
+	}
+
+	channelUUID, err := uuid.Parse(r.PathValue("channelID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse uuid, possible params")
+		return
+	}
+
+	request := CreateTextMessageRequest{}
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if strings.TrimSpace(request.Message) == "" && request.Image == "" {
+		respondWithError(w, http.StatusBadRequest, "Message is empty")
+		return
+	}
+
+	channel, err := h.DB.GetTextChannelByID(r.Context(), channelUUID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, channel.ServerID, permissions.SendMessages); !ok {
+		return
+	}
+
+	message, err := h.DB.CreateTextMessage(r.Context(), database.CreateTextMessageParams{
+		ID:        uuid.New(),
+		OwnerID:   user.ID,
+		ChannelID: channel.ID,
+		Message:   request.Message,
+		Image: sql.NullString{
+			String: request.Image,
+			Valid:  request.Image != "",
+		},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create message")
+		return
+	}
+
+	response := SimpleMessage{
+		ID:          message.ID,
+		ChannelID:   message.ChannelID,
+		OwnerID:     message.OwnerID,
+		OwnerHandle: user.Handle,
+		OwnerImage:  user.AvatarUrl.String,
+		IsBot:       user.IsBot,
+		Message:     message.Message,
+		Image:       message.Image.String,
+		CreatedAt:   message.CreatedAt,
+		UpdatedAt:   message.UpdatedAt,
+	}
+
+	err = h.Ws.BroadcastMessage(channel.ServerID, websocket.SimpleMessage(response))
+	if err != nil {
+		log.Printf("Failed to broadcast message: %v", err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
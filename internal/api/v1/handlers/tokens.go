@@ -1,20 +1,23 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
 	"github.com/jimmyvallejo/gleamspeak-api/utils"
 )
 
-func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
+const refreshTokenCachePrefix = "refresh"
 
-	const (
-		accessTokenExpirySeconds = 900
-	)
+var errRefreshTokenReplayed = errors.New("refresh token already rotated")
 
+func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("refresh_token")
 	if err != nil {
 		if err == http.ErrNoCookie {
@@ -31,8 +34,8 @@ func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims, ok := validated.Claims.(*jwt.RegisteredClaims)
-	if !ok {
+	claims, ok := validated.Claims.(*utils.Claims)
+	if !ok || claims.TokenType == utils.TokenTypeAccess {
 		respondWithError(w, http.StatusUnauthorized, "Error validating token")
 		return
 	}
@@ -60,13 +63,182 @@ func (h *Handlers) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := utils.CreateToken(id, h.JWT, 900)
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Error validating token")
+		return
+	}
+
+	stored, err := h.getRefreshToken(r.Context(), tokenID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "Token has been revoked")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Error validating token")
+		}
+		return
+	}
+
+	if stored.UserID != id {
+		respondWithError(w, http.StatusUnauthorized, "Error validating token")
+		return
+	}
+
+	if stored.RevokedAt.Valid {
+		if stored.ReplacedBy.Valid {
+			log.Printf("Refresh token reuse detected: token=%s family=%s user=%s", stored.ID, stored.FamilyID, stored.UserID)
+			if err := h.revokeSession(r.Context(), stored.UserID, stored.FamilyID); err != nil {
+				log.Printf("Failed to revoke session %s: %v", stored.FamilyID, err)
+			}
+		}
+		clearAuthCookies(w)
+		respondWithError(w, http.StatusUnauthorized, "Token has been revoked")
+		return
+	}
+
+	if time.Now().UTC().After(stored.ExpiresAt) {
+		respondWithError(w, http.StatusUnauthorized, "Token is expired")
+		return
+	}
+
+	newTokenID := uuid.New()
+
+	// The old token is only spent if its replacement is stored with it.
+	var next database.RefreshToken
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		rotated, err := db.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+			ID: stored.ID,
+			RevokedAt: sql.NullTime{
+				Time:  time.Now().UTC(),
+				Valid: true,
+			},
+			ReplacedBy: uuid.NullUUID{
+				UUID:  newTokenID,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// Another request rotated this token first, so it is being replayed.
+		if rotated == 0 {
+			return errRefreshTokenReplayed
+		}
+
+		next, err = h.createRefreshToken(r.Context(), db, newTokenID, stored.FamilyID, id)
+		if err != nil {
+			return err
+		}
+
+		return db.TouchSession(r.Context(), database.TouchSessionParams{
+			ID:         stored.FamilyID,
+			LastUsedAt: time.Now().UTC(),
+		})
+	})
+
+	h.deleteCachedRefreshTokens(stored.ID)
+
+	if errors.Is(err, errRefreshTokenReplayed) {
+		log.Printf("Refresh token reuse detected: token=%s family=%s user=%s", stored.ID, stored.FamilyID, stored.UserID)
+		if err := h.revokeSession(r.Context(), stored.UserID, stored.FamilyID); err != nil {
+			log.Printf("Failed to revoke session %s: %v", stored.FamilyID, err)
+		}
+		clearAuthCookies(w)
+		respondWithError(w, http.StatusUnauthorized, "Token has been revoked")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		return
+	}
+
+	refreshToken, err := h.signRefreshToken(next)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		return
+	}
+
+	accessToken, err := utils.CreateToken(id, stored.FamilyID, h.JWT, accessTokenExpirySeconds)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
 		return
 	}
 
 	utils.SetTokenCookie(w, "access_token", accessToken, accessTokenExpirySeconds)
+	utils.SetTokenCookie(w, "refresh_token", refreshToken, refreshTokenExpirySeconds)
 
 	respondNoBody(w, http.StatusOK)
 }
+
+// createRefreshToken persists a refresh token record in the given family. A
+// token family is the chain of refresh tokens rotated from a single login, so
+// its ID is the session ID.
+func (h *Handlers) createRefreshToken(ctx context.Context, db DBInterface, tokenID, familyID, userID uuid.UUID) (database.RefreshToken, error) {
+	now := time.Now().UTC()
+
+	return db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:        tokenID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: now.Add(refreshTokenExpirySeconds * time.Second),
+		CreatedAt: now,
+	})
+}
+
+// signRefreshToken caches a stored refresh token and returns the signed token
+// to hand to the client. Call it once the record has committed.
+func (h *Handlers) signRefreshToken(stored database.RefreshToken) (string, error) {
+	err := h.RDB.SetJson(refreshTokenCachePrefix+stored.ID.String(), stored, time.Until(stored.ExpiresAt))
+	if err != nil {
+		log.Printf("Failed to save refresh token to cache: %v", err)
+	}
+
+	return utils.CreateRefreshToken(stored.UserID, stored.FamilyID, stored.ID, h.JWT, refreshTokenExpirySeconds)
+}
+
+func (h *Handlers) getRefreshToken(ctx context.Context, tokenID uuid.UUID) (database.RefreshToken, error) {
+	var stored database.RefreshToken
+	err := h.RDB.GetJSON(refreshTokenCachePrefix+tokenID.String(), &stored)
+	if err == nil && stored.ID != uuid.Nil {
+		return stored, nil
+	}
+
+	return h.DB.GetRefreshTokenByID(ctx, tokenID)
+}
+
+func (h *Handlers) revokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) {
+	revoked, err := h.DB.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+		FamilyID: familyID,
+		RevokedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to revoke refresh token family %s: %v", familyID, err)
+		return
+	}
+
+	h.deleteCachedRefreshTokens(revoked...)
+}
+
+func (h *Handlers) deleteCachedRefreshTokens(tokenIDs ...uuid.UUID) {
+	if len(tokenIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		keys[i] = refreshTokenCachePrefix + tokenID.String()
+	}
+
+	if err := h.RDB.Delete(keys...); err != nil {
+		log.Printf("Failed to delete refresh tokens from cache: %v", err)
+	}
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+	utils.ClearTokenCookie(w, "access_token")
+	utils.ClearTokenCookie(w, "refresh_token")
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const (
+	twoFactorIssuer            = "GleamSpeak"
+	twoFactorChallengePrefix   = "2fa"
+	twoFactorAttemptsPrefix    = "2faattempts"
+	twoFactorChallengeExpiry   = 5 * time.Minute
+	twoFactorChallengeAttempts = 5
+	recoveryCodeCount          = 10
+)
+
+// twoFactorChallenge is stored under the challenge token. Attempts against it
+// are counted under a separate key with INCR so parallel guesses cannot
+// overwrite each other's count.
+type twoFactorChallenge struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (h *Handlers) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+
+	_, err = h.DB.UpsertUserTOTP(r.Context(), database.UpsertUserTOTPParams{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		}
+		return
+	}
+
+	response := TwoFactorEnrollmentResponse{
+		Secret:     secret,
+		OtpauthURI: utils.TOTPURI(twoFactorIssuer, user.Email, secret),
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (h *Handlers) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	request := TwoFactorCodeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	totp, err := h.DB.GetUserTOTP(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Two-factor enrollment not started")
+		return
+	}
+
+	if totp.EnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, valid := utils.ValidateTOTP(totp.Secret, request.Code, time.Now().UTC())
+	if !valid {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	// Enabling only commits with the recovery codes, so nobody ends up with
+	// two-factor on and no way to recover.
+	var codes []string
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		_, err := db.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			UserID: user.ID,
+			EnabledAt: sql.NullTime{
+				Time:  time.Now().UTC(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = db.UpdateUserTOTPLastUsedStep(r.Context(), database.UpdateUserTOTPLastUsedStepParams{
+			UserID:       user.ID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return err
+		}
+
+		codes, err = h.generateRecoveryCodes(r.Context(), db, user.ID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *Handlers) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	request := TwoFactorCodeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if !h.confirmIdentity(w, r, user, "", request.Code, request.RecoveryCode) {
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		err := db.DeleteUserTOTP(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+
+		return db.DeleteUserRecoveryCodes(r.Context(), user.ID)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+type CompleteTwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (h *Handlers) CompleteTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	request := CompleteTwoFactorLoginRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	ip := h.clientIP(r)
+
+	if wait := h.loginRetryAfter(loginIPSubject(ip)); wait > 0 {
+		setRetryAfter(w, wait)
+		respondWithError(w, http.StatusTooManyRequests, "Too many login attempts")
+		return
+	}
+
+	key := twoFactorChallengePrefix + request.ChallengeToken
+	attemptsKey := twoFactorAttemptsPrefix + request.ChallengeToken
+
+	var challenge twoFactorChallenge
+	err := h.RDB.GetJSON(key, &challenge)
+	if err != nil || challenge.UserID == uuid.Nil || time.Now().UTC().After(challenge.ExpiresAt) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+
+	if wait := h.loginRetryAfter(loginUserSubject(challenge.UserID)); wait > 0 {
+		setRetryAfter(w, wait)
+		respondWithError(w, http.StatusTooManyRequests, "Too many login attempts")
+		return
+	}
+
+	// The attempt is counted before the code is checked, so every guess in
+	// flight at once takes its own slot.
+	attempts, err := h.RDB.Incr(attemptsKey, time.Until(challenge.ExpiresAt))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+		return
+	}
+	if attempts > twoFactorChallengeAttempts {
+		h.dropTwoFactorChallenge(key, attemptsKey)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired challenge")
+		return
+	}
+
+	valid, err := h.verifySecondFactor(r.Context(), challenge.UserID, request.Code, request.RecoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify code")
+		return
+	}
+
+	if !valid {
+		if attempts >= twoFactorChallengeAttempts {
+			h.dropTwoFactorChallenge(key, attemptsKey)
+		}
+		if wait := h.recordTwoFactorFailure(challenge.UserID, ip); wait > 0 {
+			setRetryAfter(w, wait)
+		}
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	h.dropTwoFactorChallenge(key, attemptsKey)
+	h.resetTwoFactorFailures(challenge.UserID)
+
+	err = h.startSession(w, r, challenge.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing token")
+		return
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+func (h *Handlers) dropTwoFactorChallenge(keys ...string) {
+	if err := h.RDB.Delete(keys...); err != nil {
+		log.Printf("Failed to delete two-factor challenge: %v", err)
+	}
+}
+
+// twoFactorEnabled reports whether the user has confirmed a TOTP enrollment.
+func (h *Handlers) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := h.DB.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return totp.EnabledAt.Valid, nil
+}
+
+// issueTwoFactorChallenge stores a short-lived challenge that stands in for
+// the session until the second factor is provided.
+func (h *Handlers) issueTwoFactorChallenge(userID uuid.UUID) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := twoFactorChallenge{
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(twoFactorChallengeExpiry),
+	}
+
+	err = h.RDB.SetJson(twoFactorChallengePrefix+token, challenge, twoFactorChallengeExpiry)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery
+// code. TOTP codes are single-use: a step at or before the last accepted one
+// is rejected.
+func (h *Handlers) verifySecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		used, err := h.DB.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(recoveryCode)),
+			UsedAt: sql.NullTime{
+				Time:  time.Now().UTC(),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	totp, err := h.DB.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !totp.EnabledAt.Valid {
+		return false, nil
+	}
+
+	step, valid := utils.ValidateTOTP(totp.Secret, code, time.Now().UTC())
+	if !valid {
+		return false, nil
+	}
+
+	updated, err := h.DB.UpdateUserTOTPLastUsedStep(ctx, database.UpdateUserTOTPLastUsedStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+// generateRecoveryCodes replaces the user's recovery codes. Run it inside a
+// transaction so a failure cannot leave the user with only some of them.
+func (h *Handlers) generateRecoveryCodes(ctx context.Context, db DBInterface, userID uuid.UUID) ([]string, error) {
+	err := db.DeleteUserRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = raw[:5] + "-" + raw[5:]
+
+		err = db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  utils.HashToken(raw),
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/mail"
 	"strings"
 	"time"
 
@@ -27,6 +28,11 @@ func (h *Handlers) CreateUserStandard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validEmail(request.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
+
 	passwordBytes := []byte(request.Password)
 	hashedPasswordBytes, err := bcrypt.GenerateFromPassword(passwordBytes, 12)
 	if err != nil {
@@ -46,27 +52,33 @@ func (h *Handlers) CreateUserStandard(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: time.Now().UTC(),
 	}
 
-	user, err := h.DB.CreateUserStandard(r.Context(), params)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
-		return
-	}
+	var user database.User
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		user, err = db.CreateUserStandard(r.Context(), params)
+		if err != nil {
+			return err
+		}
+
+		roleID, err := db.GetRoleIDByName(r.Context(), "member")
+		if err != nil {
+			return err
+		}
 
-	roleID, err := h.DB.GetRoleIDByName(r.Context(), "member")
+		_, err = db.CreateUserRoles(r.Context(), database.CreateUserRolesParams{
+			UserID: user.ID,
+			RoleID: roleID,
+		})
+		return err
+	})
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error getting role ID")
+		respondWithError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
 
-	userRolesParams := database.CreateUserRolesParams{
-		UserID: user.ID,
-		RoleID: roleID,
-	}
-
-	_, err = h.DB.CreateUserRoles(r.Context(), userRolesParams)
+	err = h.sendVerificationEmail(r.Context(), user)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error assigning role")
-		return
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
 	}
 
 	response := UserResponse{
@@ -103,6 +115,13 @@ func (h *Handlers) FetchAuthUser(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, response)
 }
 
+// validEmail reports whether email is a bare address such as
+// "name@example.com", without a display name or angle brackets.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
 type UpdateUserRequest struct {
 	Email     string `json:"email"`
 	Handle    string `json:"handle"`
@@ -136,6 +155,10 @@ func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if request.Email != "" {
+		if !validEmail(request.Email) {
+			respondWithError(w, http.StatusBadRequest, "Invalid email address")
+			return
+		}
 		params.Email = request.Email
 	}
 	if request.Handle != "" {
@@ -181,6 +204,15 @@ func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		log.Printf("User saved to cache: ID=%s", user.ID)
 	}
 
+	// Changing the email drops verification, so the new address has to be
+	// confirmed before the account counts as verified again.
+	if updatedUser.Email != user.Email {
+		err = h.sendVerificationEmail(r.Context(), updatedUser)
+		if err != nil {
+			log.Printf("Failed to send verification email to %s: %v", updatedUser.Email, err)
+		}
+	}
+
 	response := UserResponse{
 		ID:     updatedUser.ID,
 		Email:  updatedUser.Email,
@@ -260,11 +292,35 @@ func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.DB.DeleteUser(r.Context(), userUUID)
+	// Boosts would otherwise vanish with the account through the foreign key
+	// and leave their servers at a level nobody pays for.
+	var unboosted []database.Server
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		serverIDs, err := db.GetUserBoostedServerIDs(r.Context(), userUUID)
+		if err != nil {
+			return err
+		}
+
+		for _, serverID := range serverIDs {
+			server, dropped, err := dropMemberBoost(r.Context(), db, userUUID, serverID)
+			if err != nil {
+				return err
+			}
+			if dropped {
+				unboosted = append(unboosted, server)
+			}
+		}
+
+		return db.DeleteUser(r.Context(), userUUID)
+	})
 	if err != nil {
 		respondWithError(w, http.StatusForbidden, "Failed to delete server")
 		return
 	}
 
+	for _, server := range unboosted {
+		h.notifyBoostChange(r, server)
+	}
+
 	respondNoBody(w, http.StatusOK)
 }
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const verificationTokenExpiry = 24 * time.Hour
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *Handlers) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	request := VerifyEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if request.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	var user database.User
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		token, err := db.UseEmailVerificationToken(r.Context(), database.UseEmailVerificationTokenParams{
+			TokenHash: utils.HashToken(request.Token),
+			UsedAt: sql.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// The token only verifies the address it was sent to; if the email
+		// has changed since, no row matches and the token is rejected.
+		user, err = db.SetUserVerified(r.Context(), database.SetUserVerifiedParams{
+			ID:        token.UserID,
+			UpdatedAt: now,
+			Email:     token.Email,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to verify email")
+		}
+		return
+	}
+
+	err = h.RDB.SetJson("user"+user.ID.String(), user, time.Hour)
+	if err != nil {
+		log.Printf("Failed to save user to cache: %v", err)
+	} else {
+		log.Printf("User saved to cache: ID=%s", user.ID)
+	}
+
+	respondNoBody(w, http.StatusOK)
+}
+
+func (h *Handlers) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if user.IsVerified.Bool {
+		respondWithError(w, http.StatusConflict, "Email is already verified")
+		return
+	}
+
+	err := h.sendVerificationEmail(r.Context(), user)
+	if err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	respondNoBody(w, http.StatusAccepted)
+}
+
+func (h *Handlers) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	_, err = h.DB.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: utils.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: now.Add(verificationTokenExpiry),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GleamSpeak email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s/verify?token=%s\n\nThe link expires in 24 hours.",
+			user.Handle, h.AppURL, token),
+	})
+}
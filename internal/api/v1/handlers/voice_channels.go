@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
 )
 
 type CreateVoiceChannelRequest struct {
@@ -40,6 +41,10 @@ func (h *Handlers) CreateVoiceChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.authorize(w, r, user.ID, serverUUID, permissions.ManageChannels); !ok {
+		return
+	}
+
 	languageID, err := h.DB.GetLanguageIDByName(r.Context(), request.Language)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request")
@@ -56,7 +61,22 @@ func (h *Handlers) CreateVoiceChannel(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   time.Now().UTC(),
 	}
 
-	channel, err := h.DB.CreateVoiceChannel(r.Context(), channelParams)
+	var channel database.VoiceChannel
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		channel, err = db.CreateVoiceChannel(r.Context(), channelParams)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   channel.ServerID,
+			ActorID:    user.ID,
+			Action:     auditVoiceChannelCreate,
+			TargetType: auditTargetVoiceChannel,
+			TargetID:   channel.ID,
+			After:      channelDetails(channel.ChannelName, channel.LanguageID),
+		})
+	})
 	if err != nil {
 		log.Printf("Error creating voice channel: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create channel")
@@ -74,7 +94,7 @@ func (h *Handlers) CreateVoiceChannel(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) LeaveVoiceChannelByUserID(w http.ResponseWriter, r *http.Request) {
-	_, ok := r.Context().Value(common.UserContextKey).(database.User)
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
 	if !ok {
 		respondWithError(w, http.StatusUnauthorized, "Unathorized")
 		return
@@ -88,6 +108,25 @@ func (h *Handlers) LeaveVoiceChannelByUserID(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Members may always leave themselves; disconnecting someone else needs
+	// MoveMembers in every server they are connected in.
+	if userUUID != user.ID {
+		memberships, err := h.DB.GetUserVoiceChannelMemberships(r.Context(), userUUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to find channel")
+			return
+		}
+		if len(memberships) == 0 {
+			respondWithError(w, http.StatusNotFound, "Failed to find channel")
+			return
+		}
+		for _, membership := range memberships {
+			if _, ok := h.authorize(w, r, user.ID, membership.ServerID, permissions.MoveMembers); !ok {
+				return
+			}
+		}
+	}
+
 	err = h.DB.LeaveVoiceChannelByUser(r.Context(), userUUID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Failed to find channel")
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const maxWebhookNameLength = 80
+
+type CreateWebhookRequest struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// CreateWebhook adds an incoming webhook to a text channel. The token is only
+// returned here; anyone holding the URL can post to the channel.
+func (h *Handlers) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	channelID, err := uuid.Parse(r.PathValue("channelID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+
+	request := CreateWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	name, ok := webhookName(w, request.Name)
+	if !ok {
+		return
+	}
+
+	channel, err := h.DB.GetTextChannelByID(r.Context(), channelID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, channel.ServerID, permissions.ManageWebhooks); !ok {
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	var webhook database.Webhook
+	err = h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		webhook, err = db.CreateWebhook(r.Context(), database.CreateWebhookParams{
+			ID:        uuid.New(),
+			ChannelID: channel.ID,
+			CreatorID: user.ID,
+			Name:      name,
+			AvatarUrl: sql.NullString{
+				String: request.AvatarURL,
+				Valid:  request.AvatarURL != "",
+			},
+			TokenHash: utils.HashToken(token),
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   channel.ServerID,
+			ActorID:    user.ID,
+			Action:     auditWebhookCreate,
+			TargetType: auditTargetWebhook,
+			TargetID:   webhook.ID,
+			After:      simpleWebhook(webhook),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, CreateWebhookResponse{
+		SimpleWebhook: simpleWebhook(webhook),
+		Token:         token,
+		URL:           "/v1/webhooks/" + webhook.ID.String() + "/" + token,
+	})
+}
+
+func (h *Handlers) GetChannelWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	channelID, err := uuid.Parse(r.PathValue("channelID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid channel ID")
+		return
+	}
+
+	channel, err := h.DB.GetTextChannelByID(r.Context(), channelID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, channel.ServerID, permissions.ManageWebhooks); !ok {
+		return
+	}
+
+	webhooks, err := h.DB.GetChannelWebhooks(r.Context(), channel.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to fetch webhooks")
+		return
+	}
+
+	simpleWebhooks := make([]SimpleWebhook, len(webhooks))
+	for i, webhook := range webhooks {
+		simpleWebhooks[i] = simpleWebhook(webhook)
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleWebhooks)
+}
+
+type UpdateWebhookRequest struct {
+	Name      *string `json:"name"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// UpdateWebhook renames a webhook or changes its default avatar. Omitted
+// fields are left as they are and an empty avatar_url clears the avatar.
+func (h *Handlers) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, webhook, serverID, ok := h.managedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	request := UpdateWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	params := database.UpdateWebhookParams{
+		ID:        webhook.ID,
+		Name:      webhook.Name,
+		AvatarUrl: webhook.AvatarUrl,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if request.Name != nil {
+		params.Name, ok = webhookName(w, *request.Name)
+		if !ok {
+			return
+		}
+	}
+	if request.AvatarURL != nil {
+		params.AvatarUrl = sql.NullString{
+			String: *request.AvatarURL,
+			Valid:  *request.AvatarURL != "",
+		}
+	}
+
+	var updated database.Webhook
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		var err error
+		updated, err = db.UpdateWebhook(r.Context(), params)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditWebhookUpdate,
+			TargetType: auditTargetWebhook,
+			TargetID:   webhook.ID,
+			Before:     simpleWebhook(webhook),
+			After:      simpleWebhook(updated),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, simpleWebhook(updated))
+}
+
+func (h *Handlers) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	user, webhook, serverID, ok := h.managedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.DB.RunInTx(r.Context(), func(db DBInterface) error {
+		if err := db.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+			return err
+		}
+
+		return recordAudit(r.Context(), db, auditEntry{
+			ServerID:   serverID,
+			ActorID:    user.ID,
+			Action:     auditWebhookDelete,
+			TargetType: auditTargetWebhook,
+			TargetID:   webhook.ID,
+			Before:     simpleWebhook(webhook),
+		})
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	respondNoBody(w, http.StatusNoContent)
+}
+
+type ExecuteWebhookRequest struct {
+	Message   string `json:"message"`
+	Image     string `json:"image"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// ExecuteWebhook posts a message to the webhook's channel. The token in the
+// path is the only credential, so an unknown webhook and a wrong token get
+// the same response. username and avatar_url override the webhook's own
+// name and avatar for this message.
+func (h *Handlers) ExecuteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	request := ExecuteWebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if strings.TrimSpace(request.Message) == "" && request.Image == "" {
+		respondWithError(w, http.StatusBadRequest, "Message is empty")
+		return
+	}
+
+	webhook, err := h.DB.GetWebhookByToken(r.Context(), database.GetWebhookByTokenParams{
+		ID:        webhookID,
+		TokenHash: utils.HashToken(r.PathValue("token")),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Webhook not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to post message")
+		}
+		return
+	}
+
+	authorName := webhook.Name
+	if request.Username != "" {
+		var ok bool
+		authorName, ok = webhookName(w, request.Username)
+		if !ok {
+			return
+		}
+	}
+
+	authorAvatar := webhook.AvatarUrl.String
+	if request.AvatarURL != "" {
+		authorAvatar = request.AvatarURL
+	}
+
+	message, err := h.DB.CreateWebhookMessage(r.Context(), database.CreateWebhookMessageParams{
+		ID:        uuid.New(),
+		OwnerID:   webhook.CreatorID,
+		ChannelID: webhook.ChannelID,
+		Message:   request.Message,
+		Image: sql.NullString{
+			String: request.Image,
+			Valid:  request.Image != "",
+		},
+		WebhookID: uuid.NullUUID{UUID: webhook.ID, Valid: true},
+		AuthorName: sql.NullString{
+			String: authorName,
+			Valid:  true,
+		},
+		AuthorAvatar: sql.NullString{
+			String: authorAvatar,
+			Valid:  authorAvatar != "",
+		},
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to post message")
+		return
+	}
+
+	response := SimpleMessage{
+		ID:          message.ID,
+		ChannelID:   message.ChannelID,
+		OwnerID:     message.OwnerID,
+		OwnerHandle: authorName,
+		OwnerImage:  authorAvatar,
+		IsBot:       true,
+		Message:     message.Message,
+		Image:       message.Image.String,
+		WebhookID:   &webhook.ID,
+		CreatedAt:   message.CreatedAt,
+		UpdatedAt:   message.UpdatedAt,
+	}
+
+	err = h.Ws.BroadcastMessage(webhook.ServerID, websocket.SimpleMessage(response))
+	if err != nil {
+		log.Printf("Failed to broadcast webhook message: %v", err)
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+// managedWebhook loads the webhook named in the path and checks that the
+// caller may manage webhooks in its server.
+func (h *Handlers) managedWebhook(w http.ResponseWriter, r *http.Request) (database.User, database.Webhook, uuid.UUID, bool) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return database.User{}, database.Webhook{}, uuid.Nil, false
+	}
+
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return database.User{}, database.Webhook{}, uuid.Nil, false
+	}
+
+	webhook, err := h.DB.GetWebhookByID(r.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Webhook not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to fetch webhook")
+		}
+		return database.User{}, database.Webhook{}, uuid.Nil, false
+	}
+
+	channel, err := h.DB.GetTextChannelByID(r.Context(), webhook.ChannelID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+		return database.User{}, database.Webhook{}, uuid.Nil, false
+	}
+
+	if _, ok := h.authorize(w, r, user.ID, channel.ServerID, permissions.ManageWebhooks); !ok {
+		return database.User{}, database.Webhook{}, uuid.Nil, false
+	}
+
+	return user, webhook, channel.ServerID, true
+}
+
+func webhookName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		respondWithError(w, http.StatusBadRequest, "Name is required")
+		return "", false
+	}
+	if len(name) > maxWebhookNameLength {
+		respondWithError(w, http.StatusBadRequest, "Name is too long")
+		return "", false
+	}
+	return name, true
+}
+
+func simpleWebhook(webhook database.Webhook) SimpleWebhook {
+	return SimpleWebhook{
+		ID:        webhook.ID,
+		ChannelID: webhook.ChannelID,
+		CreatorID: webhook.CreatorID,
+		Name:      webhook.Name,
+		Avatar:    webhook.AvatarUrl.String,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
@@ -2,15 +2,51 @@ package handlers
 
 import (
 	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
 )
 
 func (h *Handlers) HandleWebSocketUpgrade(w http.ResponseWriter, r *http.Request) {
-	userId := r.URL.Query().Get("userId")
-	handle := r.URL.Query().Get("handle")
-	if userId == "" || handle == "" {
-		http.Error(w, "Unauthorized: User ID is required", http.StatusUnauthorized)
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+	scopes, _ := r.Context().Value(common.ScopesContextKey).([]string)
+
+	h.Ws.ServeWs(w, r, user, sessionID, scopes)
+}
+
+func (h *Handlers) IssueWebSocketTicket(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.UserContextKey).(database.User)
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, _ := r.Context().Value(common.SessionContextKey).(uuid.UUID)
+
+	ticket, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing ticket")
+		return
+	}
+
+	err = h.RDB.SetJson(common.WebSocketTicketPrefix+ticket, common.WebSocketTicket{UserID: user.ID, SessionID: sessionID}, common.WebSocketTicketTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error issuing ticket")
 		return
 	}
 
-	h.Ws.ServeWs(w, r, userId, handle)
+	response := WebSocketTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(common.WebSocketTicketTTL.Seconds()),
+	}
+
+	respondWithJSON(w, http.StatusCreated, response)
 }
@@ -0,0 +1,197 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+func (f *fakeDB) GetServerAuditLog(ctx context.Context, arg database.GetServerAuditLogParams) ([]database.GetServerAuditLogRow, error) {
+	var rows []database.GetServerAuditLogRow
+	for _, entry := range f.audit {
+		if arg.Action.Valid && entry.Action != arg.Action.String {
+			continue
+		}
+		rows = append(rows, database.GetServerAuditLogRow{
+			ID:         entry.ID,
+			ServerID:   entry.ServerID,
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   entry.TargetID,
+			Before:     entry.Before,
+			After:      entry.After,
+			Reason:     entry.Reason,
+			CreatedAt:  entry.CreatedAt,
+		})
+	}
+	return rows, nil
+}
+
+// auditedRoutes maps the routes in the authorization table that write to the
+// audit log to the action they record.
+var auditedRoutes = map[string]string{
+	"UpdateServer":       "server.update",
+	"UpdateServerImages": "server.icon_update",
+	"CreateTextChannel":  "channel.create",
+	"DeleteTextChannel":  "channel.delete",
+	"CreateVoiceChannel": "voice_channel.create",
+	"CreateServerRole":   "role.create",
+	"KickMember":         "member.kick",
+	"BanMember":          "member.ban",
+	"TimeoutMember":      "member.timeout",
+	"CreateWebhook":      "webhook.create",
+}
+
+func TestAuditedRoutesRecordEntry(t *testing.T) {
+	for _, rt := range routes {
+		action, ok := auditedRoutes[rt.name]
+		if !ok {
+			continue
+		}
+
+		t.Run(rt.name, func(t *testing.T) {
+			f := newFakeDB()
+			h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+			user := database.User{ID: f.ownerID}
+			f.members[user.ID] = permissions.Default
+
+			r := rt.request(f)
+			r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			rt.handler(h)(w, r)
+
+			if w.Code != rt.success {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, rt.success, w.Body.String())
+			}
+			if len(f.audit) != 1 {
+				t.Fatalf("recorded %d audit entries, want 1", len(f.audit))
+			}
+
+			entry := f.audit[0]
+			if entry.Action != action {
+				t.Fatalf("action = %q, want %q", entry.Action, action)
+			}
+			if entry.ServerID != f.serverID {
+				t.Fatalf("server = %s, want %s", entry.ServerID, f.serverID)
+			}
+			if !entry.ActorID.Valid || entry.ActorID.UUID != user.ID {
+				t.Fatalf("actor = %v, want %s", entry.ActorID, user.ID)
+			}
+			if !json.Valid(entry.Before) || !json.Valid(entry.After) {
+				t.Fatalf("before %q or after %q is not JSON", entry.Before, entry.After)
+			}
+		})
+	}
+}
+
+func TestFailedAuditWriteRollsBackChange(t *testing.T) {
+	f := newFakeDB()
+	f.failOn = "CreateAuditLogEntry"
+	h := &handlers.Handlers{DB: f}
+
+	user := database.User{ID: f.ownerID}
+	f.members[user.ID] = permissions.Default
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/channels/text/"+f.channel.ID.String(), nil)
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.DeleteTextChannel(w, r)
+
+	if !f.failed {
+		t.Fatalf("audit entry was never written (status %d, body %q)", w.Code, w.Body.String())
+	}
+	if len(f.mutations) > 0 {
+		t.Fatalf("writes %v survived the failed audit entry", f.mutations)
+	}
+}
+
+func auditLogRequest(f *fakeDB, query string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v1/servers/"+f.serverID.String()+"/audit-log"+query, nil)
+	r.SetPathValue("serverID", f.serverID.String())
+	return r
+}
+
+func TestAuditLogRequiresPermission(t *testing.T) {
+	for _, who := range []actor{owner, moderator, member, outsider} {
+		t.Run(who.String(), func(t *testing.T) {
+			f := newFakeDB()
+			h := &handlers.Handlers{DB: f}
+
+			user := database.User{ID: uuid.New()}
+			switch who {
+			case owner:
+				f.ownerID = user.ID
+				f.members[user.ID] = permissions.Default
+			case moderator:
+				f.members[user.ID] = permissions.ViewAuditLog
+				f.positions[user.ID] = 1
+			case member:
+				f.members[user.ID] = permissions.Default
+			}
+
+			r := auditLogRequest(f, "")
+			r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			h.GetServerAuditLog(w, r)
+
+			want := http.StatusForbidden
+			if who == owner || who == moderator {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuditLogFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"action", "?action=member.kick", http.StatusOK},
+		{"unknown action", "?action=server.explode", http.StatusBadRequest},
+		{"actor", "?actor=" + uuid.NewString(), http.StatusOK},
+		{"bad actor", "?actor=nobody", http.StatusBadRequest},
+		{"window", "?since=2024-01-01T00:00:00Z&until=2024-02-01T00:00:00Z", http.StatusOK},
+		{"bad since", "?since=yesterday", http.StatusBadRequest},
+		{"bad cursor", "?cursor=%21%21", http.StatusBadRequest},
+		{"bad limit", "?limit=1000", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDB()
+			h := &handlers.Handlers{DB: f}
+
+			user := database.User{ID: f.ownerID}
+			f.members[user.ID] = permissions.Default
+
+			r := auditLogRequest(f, tt.query)
+			r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			h.GetServerAuditLog(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
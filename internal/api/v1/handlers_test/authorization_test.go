@@ -0,0 +1,569 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+// fakeDB backs a single server. Methods the routes under test do not call are
+// left to the embedded nil interface and panic if reached.
+type fakeDB struct {
+	handlers.DBInterface
+
+	serverID  uuid.UUID
+	ownerID   uuid.UUID
+	members   map[uuid.UUID]permissions.Permission
+	positions map[uuid.UUID]int32
+	muted     map[uuid.UUID]time.Time
+	channel   database.TextChannel
+	voice     map[uuid.UUID][]database.VoiceChannelMember
+	botOwner  uuid.UUID
+	webhookID uuid.UUID
+	boosts    map[uuid.UUID]bool
+	pending   map[uuid.UUID]bool
+	public    bool
+	mutations []string
+	audit     []database.CreateAuditLogEntryParams
+	// failOn names a write to fail with errInjected; failed records that it
+	// was reached.
+	failOn string
+	failed bool
+
+	// refreshTokens and revokedSessions back the token and session queries.
+	refreshTokens   map[uuid.UUID]database.RefreshToken
+	revokedSessions []uuid.UUID
+	// totp and recoveryCodes back the two-factor queries. recoveryCodes maps
+	// a code's hash to whether it was used.
+	totp          database.UserTotp
+	recoveryCodes map[string]bool
+	// users backs lookups by email.
+	users map[string]database.User
+}
+
+func newFakeDB() *fakeDB {
+	serverID := uuid.New()
+	return &fakeDB{
+		serverID:  serverID,
+		ownerID:   uuid.New(),
+		members:   make(map[uuid.UUID]permissions.Permission),
+		positions: make(map[uuid.UUID]int32),
+		muted:     make(map[uuid.UUID]time.Time),
+		channel:   database.TextChannel{ID: uuid.New(), ServerID: serverID},
+		voice:     make(map[uuid.UUID][]database.VoiceChannelMember),
+		boosts:    make(map[uuid.UUID]bool),
+		pending:   make(map[uuid.UUID]bool),
+
+		refreshTokens: make(map[uuid.UUID]database.RefreshToken),
+		recoveryCodes: make(map[string]bool),
+		users:         make(map[string]database.User),
+	}
+}
+
+// mutate records a write, or fails it if it is the write under test.
+func (f *fakeDB) mutate(name string) error {
+	if name == f.failOn {
+		f.failed = true
+		return errInjected
+	}
+	f.mutations = append(f.mutations, name)
+	return nil
+}
+
+// RunInTx discards the writes fn made if it fails, as a rolled back
+// transaction would.
+func (f *fakeDB) RunInTx(ctx context.Context, fn func(handlers.DBInterface) error) error {
+	committed := len(f.mutations)
+	if err := fn(f); err != nil {
+		f.mutations = f.mutations[:committed]
+		return err
+	}
+	return nil
+}
+
+func (f *fakeDB) GetMemberPermissions(ctx context.Context, arg database.GetMemberPermissionsParams) (database.GetMemberPermissionsRow, error) {
+	perms, ok := f.members[arg.UserID]
+	if !ok || arg.ServerID != f.serverID {
+		return database.GetMemberPermissionsRow{}, sql.ErrNoRows
+	}
+	return database.GetMemberPermissionsRow{
+		OwnerID:     f.ownerID,
+		Permissions: int64(perms),
+		TopPosition: f.positions[arg.UserID],
+		MutedUntil: sql.NullTime{
+			Time:  f.muted[arg.UserID],
+			Valid: !f.muted[arg.UserID].IsZero(),
+		},
+	}, nil
+}
+
+func (f *fakeDB) GetTextChannelByID(ctx context.Context, id uuid.UUID) (database.TextChannel, error) {
+	if id != f.channel.ID {
+		return database.TextChannel{}, sql.ErrNoRows
+	}
+	return f.channel, nil
+}
+
+func (f *fakeDB) GetLanguageIDByName(ctx context.Context, language string) (uuid.UUID, error) {
+	return uuid.New(), nil
+}
+
+func (f *fakeDB) GetUserVoiceChannelMemberships(ctx context.Context, userID uuid.UUID) ([]database.VoiceChannelMember, error) {
+	return f.voice[userID], nil
+}
+
+func (f *fakeDB) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	return database.User{ID: id, IsBot: true, BotOwnerID: uuid.NullUUID{UUID: f.botOwner, Valid: true}}, nil
+}
+
+func (f *fakeDB) GetOneServerByID(ctx context.Context, id uuid.UUID) (database.Server, error) {
+	return database.Server{ID: id, OwnerID: f.ownerID, IsPublic: sql.NullBool{Bool: f.public, Valid: true}}, nil
+}
+
+func (f *fakeDB) UpdateServerByID(ctx context.Context, arg database.UpdateServerByIDParams) (database.Server, error) {
+	if err := f.mutate("UpdateServerByID"); err != nil {
+		return database.Server{}, err
+	}
+	return database.Server{ID: arg.ID, ServerName: arg.ServerName, Description: arg.Description}, nil
+}
+
+func (f *fakeDB) UpdateServerIconByID(ctx context.Context, arg database.UpdateServerIconByIDParams) (database.UpdateServerIconByIDRow, error) {
+	if err := f.mutate("UpdateServerIconByID"); err != nil {
+		return database.UpdateServerIconByIDRow{}, err
+	}
+	return database.UpdateServerIconByIDRow{}, nil
+}
+
+func (f *fakeDB) CreateTextChannel(ctx context.Context, arg database.CreateTextChannelParams) (database.TextChannel, error) {
+	if err := f.mutate("CreateTextChannel"); err != nil {
+		return database.TextChannel{}, err
+	}
+	return database.TextChannel{ID: arg.ID, ServerID: arg.ServerID}, nil
+}
+
+func (f *fakeDB) DeleteTextChannel(ctx context.Context, id uuid.UUID) error {
+	return f.mutate("DeleteTextChannel")
+}
+
+func (f *fakeDB) CreateTextMessage(ctx context.Context, arg database.CreateTextMessageParams) (database.TextMessage, error) {
+	if err := f.mutate("CreateTextMessage"); err != nil {
+		return database.TextMessage{}, err
+	}
+	return database.TextMessage{ID: arg.ID, ChannelID: arg.ChannelID, OwnerID: arg.OwnerID, Message: arg.Message}, nil
+}
+
+func (f *fakeDB) CreateVoiceChannel(ctx context.Context, arg database.CreateVoiceChannelParams) (database.VoiceChannel, error) {
+	if err := f.mutate("CreateVoiceChannel"); err != nil {
+		return database.VoiceChannel{}, err
+	}
+	return database.VoiceChannel{ID: arg.ID, ServerID: arg.ServerID}, nil
+}
+
+func (f *fakeDB) LeaveVoiceChannelByUser(ctx context.Context, userID uuid.UUID) error {
+	return f.mutate("LeaveVoiceChannelByUser")
+}
+
+func (f *fakeDB) CreateUserServer(ctx context.Context, arg database.CreateUserServerParams) (database.UserServer, error) {
+	if err := f.mutate("CreateUserServer"); err != nil {
+		return database.UserServer{}, err
+	}
+	return database.UserServer{UserID: arg.UserID, ServerID: arg.ServerID}, nil
+}
+
+func (f *fakeDB) JoinServerMember(ctx context.Context, arg database.JoinServerMemberParams) (database.UserServer, error) {
+	if err := f.mutate("JoinServerMember"); err != nil {
+		return database.UserServer{}, err
+	}
+	return database.UserServer{UserID: arg.UserID, ServerID: arg.ServerID}, nil
+}
+
+func (f *fakeDB) CreateServerRole(ctx context.Context, arg database.CreateServerRoleParams) (database.ServerRole, error) {
+	if err := f.mutate("CreateServerRole"); err != nil {
+		return database.ServerRole{}, err
+	}
+	return database.ServerRole{ID: arg.ID, ServerID: arg.ServerID, Name: arg.Name}, nil
+}
+
+func (f *fakeDB) GetActiveBan(ctx context.Context, arg database.GetActiveBanParams) (database.ServerBan, error) {
+	return database.ServerBan{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) RemoveServerMember(ctx context.Context, arg database.RemoveServerMemberParams) (int64, error) {
+	if err := f.mutate("RemoveServerMember"); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (f *fakeDB) BanMember(ctx context.Context, arg database.BanMemberParams) (database.BanMemberRow, error) {
+	if err := f.mutate("BanMember"); err != nil {
+		return database.BanMemberRow{}, err
+	}
+	return database.BanMemberRow{ServerID: arg.ServerID, UserID: arg.UserID, Reason: arg.Reason, RemovedMembers: 1}, nil
+}
+
+func (f *fakeDB) SetMemberMutedUntil(ctx context.Context, arg database.SetMemberMutedUntilParams) (int64, error) {
+	if err := f.mutate("SetMemberMutedUntil"); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (f *fakeDB) LeaveServerVoiceChannels(ctx context.Context, arg database.LeaveServerVoiceChannelsParams) error {
+	return f.mutate("LeaveServerVoiceChannels")
+}
+
+func (f *fakeDB) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	if err := f.mutate("CreateAuditLogEntry"); err != nil {
+		return err
+	}
+	f.audit = append(f.audit, arg)
+	return nil
+}
+
+// target adds a plain member for the moderation routes to act on.
+func (f *fakeDB) target() uuid.UUID {
+	id := uuid.New()
+	f.members[id] = permissions.Default
+	return id
+}
+
+type actor int
+
+const (
+	owner actor = iota
+	moderator
+	member
+	outsider
+)
+
+func (a actor) String() string {
+	return [...]string{"owner", "moderator", "member", "outsider"}[a]
+}
+
+type route struct {
+	name    string
+	handler func(h *handlers.Handlers) http.HandlerFunc
+	// request builds the request for the route against the fake server.
+	request func(f *fakeDB) *http.Request
+	// moderator is the permission the moderator actor is granted for the route.
+	moderator permissions.Permission
+	success   int
+}
+
+func jsonRequest(method, target string, body any) *http.Request {
+	payload, _ := json.Marshal(body)
+	return httptest.NewRequest(method, target, bytes.NewReader(payload))
+}
+
+var routes = []route{
+	{
+		name:    "UpdateServer",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.UpdateServer },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPut, "/v1/servers", map[string]any{
+				"server_id":   f.serverID,
+				"server_name": "renamed",
+				"description": "new description",
+			})
+		},
+		moderator: permissions.ManageServer,
+		success:   http.StatusOK,
+	},
+	{
+		name:    "UpdateServerImages",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.UpdateServerImages },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPut, "/v1/servers/images", map[string]any{
+				"server_id": f.serverID,
+				"is_icon":   true,
+				"url":       "https://example.com/icon.png",
+			})
+		},
+		moderator: permissions.ManageServer,
+		success:   http.StatusOK,
+	},
+	{
+		name:    "CreateTextChannel",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateTextChannel },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/channels/text", map[string]any{
+				"server_id":    f.serverID.String(),
+				"language":     "English",
+				"channel_name": "general",
+			})
+		},
+		moderator: permissions.ManageChannels,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "DeleteTextChannel",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.DeleteTextChannel },
+		request: func(f *fakeDB) *http.Request {
+			return httptest.NewRequest(http.MethodDelete, "/v1/channels/text/"+f.channel.ID.String(), nil)
+		},
+		moderator: permissions.ManageChannels,
+		success:   http.StatusOK,
+	},
+	{
+		name:    "CreateVoiceChannel",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateVoiceChannel },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/channels/voice", map[string]any{
+				"server_id":    f.serverID.String(),
+				"language":     "English",
+				"channel_name": "lounge",
+			})
+		},
+		moderator: permissions.ManageChannels,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "LeaveVoiceChannelByUserID",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.LeaveVoiceChannelByUserID },
+		request: func(f *fakeDB) *http.Request {
+			target := uuid.New()
+			f.voice[target] = []database.VoiceChannelMember{{UserID: target, ServerID: f.serverID}}
+			return httptest.NewRequest(http.MethodDelete, "/v1/channels/voice/"+target.String(), nil)
+		},
+		moderator: permissions.MoveMembers,
+		success:   http.StatusOK,
+	},
+	{
+		name:    "CreateChannelTextMessage",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateChannelTextMessage },
+		request: func(f *fakeDB) *http.Request {
+			r := jsonRequest(http.MethodPost, "/v1/messages/"+f.channel.ID.String(), map[string]any{"message": "hello"})
+			r.SetPathValue("channelID", f.channel.ID.String())
+			return r
+		},
+		moderator: permissions.SendMessages,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "CreateWebhook",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateWebhook },
+		request: func(f *fakeDB) *http.Request {
+			r := jsonRequest(http.MethodPost, "/v1/channels/text/"+f.channel.ID.String()+"/webhooks", map[string]any{"name": "CI"})
+			r.SetPathValue("channelID", f.channel.ID.String())
+			return r
+		},
+		moderator: permissions.ManageWebhooks,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "InviteBot",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.InviteBot },
+		request: func(f *fakeDB) *http.Request {
+			botID := uuid.New()
+			r := jsonRequest(http.MethodPost, "/v1/bots/"+botID.String()+"/servers", map[string]any{"server_id": f.serverID})
+			r.SetPathValue("botID", botID.String())
+			return r
+		},
+		moderator: permissions.ManageServer,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "CreateServerRole",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateServerRole },
+		request: func(f *fakeDB) *http.Request {
+			r := jsonRequest(http.MethodPost, "/v1/servers/"+f.serverID.String()+"/roles", map[string]any{
+				"name":        "Helper",
+				"permissions": int64(permissions.ManageRoles),
+			})
+			r.SetPathValue("serverID", f.serverID.String())
+			return r
+		},
+		moderator: permissions.ManageRoles,
+		success:   http.StatusCreated,
+	},
+	{
+		name:    "KickMember",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.KickMember },
+		request: func(f *fakeDB) *http.Request {
+			target := f.target()
+			r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/members/"+target.String(), nil)
+			r.SetPathValue("serverID", f.serverID.String())
+			r.SetPathValue("userID", target.String())
+			return r
+		},
+		moderator: permissions.KickMembers,
+		success:   http.StatusNoContent,
+	},
+	{
+		name:    "BanMember",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.BanMember },
+		request: func(f *fakeDB) *http.Request {
+			target := f.target()
+			r := jsonRequest(http.MethodPut, "/v1/servers/"+f.serverID.String()+"/bans/"+target.String(), map[string]any{
+				"reason":     "spam",
+				"expires_in": 3600,
+			})
+			r.SetPathValue("serverID", f.serverID.String())
+			r.SetPathValue("userID", target.String())
+			return r
+		},
+		moderator: permissions.BanMembers,
+		success:   http.StatusOK,
+	},
+	{
+		name:    "TimeoutMember",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.TimeoutMember },
+		request: func(f *fakeDB) *http.Request {
+			target := f.target()
+			r := jsonRequest(http.MethodPut, "/v1/servers/"+f.serverID.String()+"/members/"+target.String()+"/timeout", map[string]any{
+				"duration": 600,
+			})
+			r.SetPathValue("serverID", f.serverID.String())
+			r.SetPathValue("userID", target.String())
+			return r
+		},
+		moderator: permissions.TimeoutMembers,
+		success:   http.StatusOK,
+	},
+}
+
+func TestMutationsRequirePermission(t *testing.T) {
+	for _, rt := range routes {
+		for _, who := range []actor{owner, moderator, member, outsider} {
+			t.Run(rt.name+"/"+who.String(), func(t *testing.T) {
+				f := newFakeDB()
+				h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+				user := database.User{ID: uuid.New(), Handle: who.String()}
+				switch who {
+				case owner:
+					f.ownerID = user.ID
+					f.members[user.ID] = permissions.Default
+				case moderator:
+					// Grant only the route's permission, above the default role.
+					f.members[user.ID] = rt.moderator
+					f.positions[user.ID] = 1
+				case member:
+					f.members[user.ID] = permissions.Default &^ rt.moderator
+				}
+				f.botOwner = user.ID
+
+				r := rt.request(f)
+				r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+				w := httptest.NewRecorder()
+
+				rt.handler(h)(w, r)
+
+				allowed := who == owner || who == moderator
+				want := http.StatusForbidden
+				if allowed {
+					want = rt.success
+				}
+
+				if w.Code != want {
+					t.Fatalf("status = %d, want %d (body %q)", w.Code, want, w.Body.String())
+				}
+				if !allowed && len(f.mutations) > 0 {
+					t.Fatalf("forbidden request reached %v", f.mutations)
+				}
+				if allowed && len(f.mutations) == 0 {
+					t.Fatal("allowed request did not reach the database")
+				}
+			})
+		}
+	}
+}
+
+func TestLeaveVoiceChannelSelf(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f}
+
+	user := database.User{ID: uuid.New()}
+	r := httptest.NewRequest(http.MethodDelete, "/v1/channels/voice/"+user.ID.String(), nil)
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.LeaveVoiceChannelByUserID(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestModeratorCannotActOnHigherRole(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+	user := database.User{ID: uuid.New()}
+	f.members[user.ID] = permissions.KickMembers
+	f.positions[user.ID] = 1
+
+	target := f.target()
+	f.positions[target] = 1
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/members/"+target.String(), nil)
+	r.SetPathValue("serverID", f.serverID.String())
+	r.SetPathValue("userID", target.String())
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.KickMember(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if len(f.mutations) > 0 {
+		t.Fatalf("forbidden request reached %v", f.mutations)
+	}
+}
+
+func TestTimedOutMemberCannotSend(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f}
+
+	user := database.User{ID: uuid.New()}
+	f.members[user.ID] = permissions.Default
+	f.muted[user.ID] = time.Now().Add(time.Hour)
+
+	r := jsonRequest(http.MethodPost, "/v1/messages/"+f.channel.ID.String(), map[string]any{"message": "hello"})
+	r.SetPathValue("channelID", f.channel.ID.String())
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.CreateChannelTextMessage(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAdministratorImpliesEveryPermission(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms permissions.Permission
+		check permissions.Permission
+		want  bool
+	}{
+		{"administrator", permissions.Administrator, permissions.ManageChannels, true},
+		{"exact bit", permissions.ManageChannels, permissions.ManageChannels, true},
+		{"default lacks manage", permissions.Default, permissions.ManageServer, false},
+		{"default sends", permissions.Default, permissions.SendMessages, true},
+		{"partial combination", permissions.KickMembers, permissions.KickMembers | permissions.BanMembers, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.perms.Has(tt.check); got != tt.want {
+				t.Fatalf("Has = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
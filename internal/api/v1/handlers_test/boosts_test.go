@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+func (f *fakeDB) RemoveServerBoost(ctx context.Context, arg database.RemoveServerBoostParams) (database.Server, error) {
+	if arg.ServerID != f.serverID || !f.boosts[arg.UserID] {
+		return database.Server{}, sql.ErrNoRows
+	}
+	if err := f.mutate("RemoveServerBoost"); err != nil {
+		return database.Server{}, err
+	}
+	delete(f.boosts, arg.UserID)
+	return database.Server{ID: f.serverID, OwnerID: f.ownerID, BoostCount: int32(len(f.boosts))}, nil
+}
+
+func (f *fakeDB) GetUserBoostedServerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	if !f.boosts[userID] {
+		return nil, nil
+	}
+	return []uuid.UUID{f.serverID}, nil
+}
+
+func (f *fakeDB) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return f.mutate("DeleteUser")
+}
+
+func removeBoost(t *testing.T, f *fakeDB, user database.User) *httptest.ResponseRecorder {
+	t.Helper()
+
+	h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/boosts", nil)
+	r.SetPathValue("serverID", f.serverID.String())
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.RemoveServerBoost(w, r)
+	return w
+}
+
+func TestRemoveServerBoost(t *testing.T) {
+	f := newFakeDB()
+
+	user := database.User{ID: f.ownerID}
+	f.members[user.ID] = permissions.Default
+	f.boosts[user.ID] = true
+
+	w := removeBoost(t, f, user)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+	if f.boosts[user.ID] {
+		t.Fatal("boost was not removed")
+	}
+
+	var server handlers.SimpleServer
+	if err := json.NewDecoder(w.Body).Decode(&server); err != nil {
+		t.Fatal(err)
+	}
+	if server.ServerID != f.serverID {
+		t.Fatalf("server = %s, want %s", server.ServerID, f.serverID)
+	}
+}
+
+func TestRemoveServerBoostWithoutBoost(t *testing.T) {
+	f := newFakeDB()
+
+	user := database.User{ID: f.ownerID}
+	f.members[user.ID] = permissions.Default
+
+	w := removeBoost(t, f, user)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
+
+func TestKickWithdrawsBoost(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+	owner := database.User{ID: f.ownerID}
+	f.members[owner.ID] = permissions.Default
+
+	target := f.target()
+	f.boosts[target] = true
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/members/"+target.String(), nil)
+	r.SetPathValue("serverID", f.serverID.String())
+	r.SetPathValue("userID", target.String())
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, owner))
+	w := httptest.NewRecorder()
+
+	h.KickMember(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusNoContent, w.Body.String())
+	}
+	if f.boosts[target] {
+		t.Fatal("kicked member's boost still counts")
+	}
+}
+
+func TestGetSignedURLRequiresFilesize(t *testing.T) {
+	h := &handlers.Handlers{DB: newFakeDB()}
+
+	for _, size := range []int64{0, -1} {
+		r := jsonRequest(http.MethodPost, "/v1/s3/url", map[string]any{
+			"filename": "cat.png",
+			"filetype": "image/png",
+			"filesize": size,
+		})
+		r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, database.User{ID: uuid.New()}))
+		w := httptest.NewRecorder()
+
+		h.GetSignedURL(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("filesize %d: status = %d, want %d", size, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestDeleteUserWithdrawsBoosts(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+	user := database.User{ID: f.target()}
+	f.boosts[user.ID] = true
+
+	r := httptest.NewRequest(http.MethodDelete, "/v1/users/"+user.ID.String(), nil)
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.DeleteUser(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+	if f.boosts[user.ID] {
+		t.Fatal("deleted user's boost still counts")
+	}
+	want := []string{"RemoveServerBoost", "DeleteUser"}
+	if !slices.Equal(f.mutations, want) {
+		t.Fatalf("writes = %v, want %v", f.mutations, want)
+	}
+}
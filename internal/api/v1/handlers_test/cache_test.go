@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
+)
+
+var errCacheMiss = errors.New("cache miss")
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// fakeCache is an in-memory handlers.Cache. Entries expire against its own
+// clock, which advance moves forward.
+type fakeCache struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]cacheEntry
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{
+		now:     time.Now(),
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// advance moves the cache's clock forward by d.
+func (c *fakeCache) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// get returns the live entry under key. Callers hold c.mu.
+func (c *fakeCache) get(key string) (cacheEntry, bool) {
+	entry, ok := c.entries[key]
+	if ok && !entry.expires.IsZero() && !c.now.Before(entry.expires) {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	return entry, ok
+}
+
+func (c *fakeCache) set(key string, value []byte, expiration time.Duration) {
+	entry := cacheEntry{value: value}
+	if expiration > 0 {
+		entry.expires = c.now.Add(expiration)
+	}
+	c.entries[key] = entry
+}
+
+func (c *fakeCache) GetJSON(key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.get(key)
+	if !ok {
+		return errCacheMiss
+	}
+	return json.Unmarshal(entry.value, dest)
+}
+
+func (c *fakeCache) SetJson(key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.set(key, encoded, expiration)
+	return nil
+}
+
+func (c *fakeCache) GetDelJSON(key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.get(key)
+	if !ok {
+		return errCacheMiss
+	}
+	delete(c.entries, key)
+	return json.Unmarshal(entry.value, dest)
+}
+
+func (c *fakeCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *fakeCache) Incr(key string, expiration time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.get(key)
+	if !ok {
+		c.set(key, []byte("1"), expiration)
+		return 1, nil
+	}
+
+	count, err := strconv.ParseInt(string(entry.value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	count++
+	entry.value = []byte(strconv.FormatInt(count, 10))
+	c.entries[key] = entry
+	return count, nil
+}
+
+func (c *fakeCache) TTL(key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.get(key)
+	if !ok || entry.expires.IsZero() {
+		return 0, nil
+	}
+	return entry.expires.Sub(c.now), nil
+}
+
+// keyStore is an in-memory jwtkeys.Store.
+type keyStore struct {
+	keys []database.SigningKey
+}
+
+func (s *keyStore) CreateSigningKey(ctx context.Context, arg database.CreateSigningKeyParams) (database.SigningKey, error) {
+	key := database.SigningKey{
+		ID:         arg.ID,
+		Algorithm:  arg.Algorithm,
+		PrivateKey: arg.PrivateKey,
+		CreatedAt:  arg.CreatedAt,
+	}
+	s.keys = append(s.keys, key)
+	return key, nil
+}
+
+func (s *keyStore) GetUnexpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) ([]database.SigningKey, error) {
+	return s.keys, nil
+}
+
+func (s *keyStore) RetireSigningKeys(ctx context.Context, arg database.RetireSigningKeysParams) error {
+	return nil
+}
+
+func (s *keyStore) DeleteExpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) error {
+	return nil
+}
+
+func (s *keyStore) LockSigningKeys(ctx context.Context) error {
+	return nil
+}
+
+func (s *keyStore) RunInTx(ctx context.Context, fn func(jwtkeys.Store) error) error {
+	return fn(s)
+}
+
+// newKeySet returns a key set holding one fresh signing key.
+func newKeySet(t *testing.T) *jwtkeys.KeySet {
+	t.Helper()
+
+	keys, err := jwtkeys.New(context.Background(), &keyStore{}, jwtkeys.Config{
+		RotateEvery:      time.Hour,
+		VerifyFor:        time.Hour,
+		EncryptionSecret: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/lib/pq"
+)
+
+func (f *fakeDB) GetUserServer(ctx context.Context, arg database.GetUserServerParams) (database.UserServer, error) {
+	if _, ok := f.members[arg.UserID]; !ok || arg.ServerID != f.serverID {
+		return database.UserServer{}, sql.ErrNoRows
+	}
+	return database.UserServer{UserID: arg.UserID, ServerID: arg.ServerID}, nil
+}
+
+// CreateJoinRequest fails the way the pending request index does for users
+// in f.pending.
+func (f *fakeDB) CreateJoinRequest(ctx context.Context, arg database.CreateJoinRequestParams) (database.JoinRequest, error) {
+	if f.pending[arg.UserID] {
+		return database.JoinRequest{}, &pq.Error{Code: "23505", Constraint: "idx_join_requests_pending"}
+	}
+	if err := f.mutate("CreateJoinRequest"); err != nil {
+		return database.JoinRequest{}, err
+	}
+	return database.JoinRequest{ID: arg.ID, ServerID: arg.ServerID, UserID: arg.UserID, Status: "pending"}, nil
+}
+
+func TestCreateJoinRequestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending bool
+		failOn  string
+		want    int
+	}{
+		{"created", false, "", http.StatusCreated},
+		{"already pending", true, "", http.StatusConflict},
+		{"database failure", false, "CreateJoinRequest", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDB()
+			f.failOn = tt.failOn
+			h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+			user := database.User{ID: uuid.New()}
+			f.pending[user.ID] = tt.pending
+
+			r := jsonRequest(http.MethodPost, "/v1/servers/"+f.serverID.String()+"/join-requests", map[string]any{"message": "hi"})
+			r.SetPathValue("serverID", f.serverID.String())
+			r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			h.CreateJoinRequest(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (f *fakeDB) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	user, ok := f.users[email]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// fakeMailer records the messages it is asked to send.
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+const loginPassword = "correct horse"
+
+type loginFixture struct {
+	f      *fakeDB
+	cache  *fakeCache
+	mail   *fakeMailer
+	h      *handlers.Handlers
+	limits handlers.LoginLimits
+	user   database.User
+}
+
+func newLoginFixture(t *testing.T, limits handlers.LoginLimits) *loginFixture {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(loginPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fx := &loginFixture{
+		f:      newFakeDB(),
+		cache:  newFakeCache(),
+		mail:   &fakeMailer{},
+		limits: limits,
+		user: database.User{
+			ID:       uuid.New(),
+			Email:    "user@example.com",
+			Handle:   "user",
+			Password: sql.NullString{String: string(hash), Valid: true},
+		},
+	}
+	fx.f.users[fx.user.Email] = fx.user
+	fx.h = &handlers.Handlers{
+		DB:          fx.f,
+		RDB:         fx.cache,
+		JWT:         newKeySet(t),
+		Ws:          websocket.NewManager(nil, nil),
+		Mailer:      fx.mail,
+		LoginLimits: limits,
+	}
+	return fx
+}
+
+func (fx *loginFixture) login(email, password string) *httptest.ResponseRecorder {
+	r := jsonRequest(http.MethodPost, "/v1/login", map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	w := httptest.NewRecorder()
+
+	fx.h.LoginUserStandard(w, r)
+	return w
+}
+
+// fail makes a failed login and returns the Retry-After it was answered with.
+func (fx *loginFixture) fail(t *testing.T, email string) string {
+	t.Helper()
+
+	w := fx.login(email, "wrong")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("failed login: status = %d, want %d (body %q)", w.Code, http.StatusNotFound, w.Body.String())
+	}
+	return w.Header().Get("Retry-After")
+}
+
+func TestLoginBacksOffExponentially(t *testing.T) {
+	fx := newLoginFixture(t, handlers.DefaultLoginLimits())
+
+	for i := int64(0); i < fx.limits.FreeAttempts; i++ {
+		if retry := fx.fail(t, fx.user.Email); retry != "" {
+			t.Fatalf("free attempt %d: Retry-After %q, want none", i+1, retry)
+		}
+	}
+
+	for _, want := range []string{"1", "2", "4", "8"} {
+		retry := fx.fail(t, fx.user.Email)
+		if retry != want {
+			t.Fatalf("Retry-After %q, want %q", retry, want)
+		}
+
+		w := fx.login(fx.user.Email, loginPassword)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+			t.Fatalf("login during backoff: status = %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+		}
+
+		fx.cache.advance(fx.limits.BaseDelay * 8)
+	}
+}
+
+func TestLoginLocksOutAfterMaxAttempts(t *testing.T) {
+	fx := newLoginFixture(t, handlers.DefaultLoginLimits())
+
+	var retry string
+	for i := int64(0); i < fx.limits.MaxAttempts; i++ {
+		retry = fx.fail(t, fx.user.Email)
+		if i < fx.limits.MaxAttempts-1 {
+			fx.cache.advance(fx.limits.BaseDelay * 64)
+		}
+	}
+
+	want := fmt.Sprint(int(fx.limits.LockoutDuration.Seconds()))
+	if retry != want {
+		t.Fatalf("Retry-After %q at the max attempt, want %q", retry, want)
+	}
+
+	if len(fx.mail.sent) != 1 || fx.mail.sent[0].To != fx.user.Email {
+		t.Fatalf("sent %d lockout emails, want 1 to %s", len(fx.mail.sent), fx.user.Email)
+	}
+
+	if w := fx.login(fx.user.Email, loginPassword); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("correct password during lockout: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	fx.cache.advance(fx.limits.LockoutDuration)
+
+	if w := fx.login(fx.user.Email, loginPassword); w.Code != http.StatusOK {
+		t.Fatalf("correct password after lockout: status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+}
+
+func TestLoginFailuresExpireAfterWindow(t *testing.T) {
+	fx := newLoginFixture(t, handlers.DefaultLoginLimits())
+
+	for i := int64(0); i < fx.limits.FreeAttempts; i++ {
+		fx.fail(t, fx.user.Email)
+	}
+
+	fx.cache.advance(fx.limits.Window)
+
+	if retry := fx.fail(t, fx.user.Email); retry != "" {
+		t.Fatalf("Retry-After %q after the window passed, want none", retry)
+	}
+}
+
+// TestLoginSuccessKeepsIPFailures guesses passwords for other emails from
+// one address between logins to an account the guesser controls. The
+// successful logins must not reset the address's count.
+func TestLoginSuccessKeepsIPFailures(t *testing.T) {
+	limits := handlers.DefaultLoginLimits()
+	limits.IPMaxAttempts = 5
+	fx := newLoginFixture(t, limits)
+
+	for i := int64(0); i < limits.IPMaxAttempts; i++ {
+		if w := fx.login(fx.user.Email, loginPassword); w.Code != http.StatusOK {
+			t.Fatalf("login %d: status = %d, want %d (body %q)", i+1, w.Code, http.StatusOK, w.Body.String())
+		}
+
+		retry := fx.fail(t, fmt.Sprintf("victim%d@example.com", i))
+		fx.cache.advance(limits.BaseDelay * 8)
+
+		if i == limits.IPMaxAttempts-1 {
+			want := fmt.Sprint(int(limits.LockoutDuration.Seconds()))
+			if retry != want {
+				t.Fatalf("Retry-After %q at the address's max attempt, want %q", retry, want)
+			}
+		}
+	}
+
+	if len(fx.mail.sent) != 0 {
+		t.Fatalf("sent %d lockout emails for unknown addresses, want none", len(fx.mail.sent))
+	}
+}
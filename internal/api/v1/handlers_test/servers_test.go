@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+)
+
+func TestJoinServerByID(t *testing.T) {
+	tests := []struct {
+		name   string
+		member bool
+		want   int
+	}{
+		{"new member", false, http.StatusCreated},
+		{"already a member", true, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDB()
+			f.public = true
+			h := &handlers.Handlers{DB: f}
+
+			user := database.User{ID: uuid.New()}
+			if tt.member {
+				f.members[user.ID] = permissions.Default
+			}
+
+			r := jsonRequest(http.MethodPost, "/v1/servers/join", map[string]any{"server_id": f.serverID})
+			r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+			w := httptest.NewRecorder()
+
+			h.JoinServerByID(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.member && len(f.mutations) > 0 {
+				t.Fatalf("duplicate join reached %v", f.mutations)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+func (f *fakeDB) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	if err := f.mutate("CreateRefreshToken"); err != nil {
+		return database.RefreshToken{}, err
+	}
+	token := database.RefreshToken{
+		ID:        arg.ID,
+		FamilyID:  arg.FamilyID,
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: arg.CreatedAt,
+	}
+	f.refreshTokens[token.ID] = token
+	return token, nil
+}
+
+func (f *fakeDB) GetRefreshTokenByID(ctx context.Context, id uuid.UUID) (database.RefreshToken, error) {
+	token, ok := f.refreshTokens[id]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+// RotateRefreshToken spends the token only if it is still unrevoked, as the
+// conditional UPDATE does.
+func (f *fakeDB) RotateRefreshToken(ctx context.Context, arg database.RotateRefreshTokenParams) (int64, error) {
+	token, ok := f.refreshTokens[arg.ID]
+	if !ok || token.RevokedAt.Valid {
+		return 0, nil
+	}
+	if err := f.mutate("RotateRefreshToken"); err != nil {
+		return 0, err
+	}
+	token.RevokedAt = arg.RevokedAt
+	token.ReplacedBy = arg.ReplacedBy
+	f.refreshTokens[token.ID] = token
+	return 1, nil
+}
+
+func (f *fakeDB) RevokeRefreshTokenFamily(ctx context.Context, arg database.RevokeRefreshTokenFamilyParams) ([]uuid.UUID, error) {
+	var revoked []uuid.UUID
+	for id, token := range f.refreshTokens {
+		if token.FamilyID == arg.FamilyID && !token.RevokedAt.Valid {
+			token.RevokedAt = arg.RevokedAt
+			f.refreshTokens[id] = token
+			revoked = append(revoked, id)
+		}
+	}
+	return revoked, nil
+}
+
+func (f *fakeDB) TouchSession(ctx context.Context, arg database.TouchSessionParams) error {
+	return f.mutate("TouchSession")
+}
+
+func (f *fakeDB) RevokeSession(ctx context.Context, arg database.RevokeSessionParams) (database.Session, error) {
+	f.revokedSessions = append(f.revokedSessions, arg.ID)
+	return database.Session{ID: arg.ID, UserID: arg.UserID, RevokedAt: arg.RevokedAt}, nil
+}
+
+type refreshFixture struct {
+	f     *fakeDB
+	cache *fakeCache
+	keys  *jwtkeys.KeySet
+	h     *handlers.Handlers
+
+	userID   uuid.UUID
+	familyID uuid.UUID
+}
+
+func newRefreshFixture(t *testing.T) *refreshFixture {
+	t.Helper()
+
+	fx := &refreshFixture{
+		f:        newFakeDB(),
+		cache:    newFakeCache(),
+		keys:     newKeySet(t),
+		userID:   uuid.New(),
+		familyID: uuid.New(),
+	}
+	fx.h = &handlers.Handlers{DB: fx.f, RDB: fx.cache, JWT: fx.keys, Ws: websocket.NewManager(nil, nil)}
+	return fx
+}
+
+// issue stores a live refresh token in the fixture's family and returns it
+// signed.
+func (fx *refreshFixture) issue(t *testing.T) (uuid.UUID, string) {
+	t.Helper()
+
+	id := uuid.New()
+	fx.f.refreshTokens[id] = database.RefreshToken{
+		ID:        id,
+		FamilyID:  fx.familyID,
+		UserID:    fx.userID,
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	signed, err := utils.CreateRefreshToken(fx.userID, fx.familyID, id, fx.keys, 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, signed
+}
+
+func (fx *refreshFixture) refresh(token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", nil)
+	r.AddCookie(&http.Cookie{Name: "refresh_token", Value: token})
+	w := httptest.NewRecorder()
+
+	fx.h.RefreshToken(w, r)
+	return w
+}
+
+// cookie returns the value w set for name, or "" if it set none.
+func cookie(w *httptest.ResponseRecorder, name string) string {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// assertFamilyRevoked checks every token in the fixture's family is revoked
+// and its session was ended.
+func (fx *refreshFixture) assertFamilyRevoked(t *testing.T) {
+	t.Helper()
+
+	for id, token := range fx.f.refreshTokens {
+		if token.FamilyID == fx.familyID && !token.RevokedAt.Valid {
+			t.Fatalf("token %s in the reused family is still live", id)
+		}
+	}
+	if !slices.Contains(fx.f.revokedSessions, fx.familyID) {
+		t.Fatalf("session %s was not revoked", fx.familyID)
+	}
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	fx := newRefreshFixture(t)
+	oldID, token := fx.issue(t)
+
+	w := fx.refresh(token)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	next := cookie(w, "refresh_token")
+	if next == "" || cookie(w, "access_token") == "" {
+		t.Fatal("refresh did not set new token cookies")
+	}
+
+	validated, err := utils.ValidateToken(next, fx.keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := validated.Claims.(*utils.Claims)
+	newID := uuid.MustParse(claims.ID)
+
+	old := fx.f.refreshTokens[oldID]
+	if !old.RevokedAt.Valid || old.ReplacedBy.UUID != newID {
+		t.Fatalf("old token revoked=%v replaced_by=%v, want it replaced by %s", old.RevokedAt.Valid, old.ReplacedBy.UUID, newID)
+	}
+
+	stored, ok := fx.f.refreshTokens[newID]
+	if !ok || stored.FamilyID != fx.familyID || stored.RevokedAt.Valid {
+		t.Fatalf("new token %s was not stored live in the family", newID)
+	}
+
+	want := []string{"RotateRefreshToken", "CreateRefreshToken", "TouchSession"}
+	if !slices.Equal(fx.f.mutations, want) {
+		t.Fatalf("writes = %v, want %v", fx.f.mutations, want)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	fx := newRefreshFixture(t)
+	_, token := fx.issue(t)
+
+	if w := fx.refresh(token); w.Code != http.StatusOK {
+		t.Fatalf("first refresh: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w := fx.refresh(token)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+	fx.assertFamilyRevoked(t)
+}
+
+// TestRefreshTokenRaceRevokesFamily replays a token whose rotation another
+// request has committed but this one still sees as live, as two requests
+// racing with the same token would.
+func TestRefreshTokenRaceRevokesFamily(t *testing.T) {
+	fx := newRefreshFixture(t)
+	id, token := fx.issue(t)
+
+	if err := fx.cache.SetJson("refresh"+id.String(), fx.f.refreshTokens[id], time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	spent := fx.f.refreshTokens[id]
+	spent.RevokedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	spent.ReplacedBy = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	fx.f.refreshTokens[id] = spent
+	fx.issue(t)
+
+	w := fx.refresh(token)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+	if slices.Contains(fx.f.mutations, "CreateRefreshToken") {
+		t.Fatal("a replacement was issued for a replayed token")
+	}
+	fx.assertFamilyRevoked(t)
+}
+
+func TestRefreshTokenRejectsAccessToken(t *testing.T) {
+	fx := newRefreshFixture(t)
+	fx.issue(t)
+
+	access, err := utils.CreateToken(fx.userID, fx.familyID, fx.keys, 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := fx.refresh(access)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+	if len(fx.f.mutations) > 0 {
+		t.Fatalf("writes %v were made for an access token", fx.f.mutations)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+var errInjected = errors.New("injected failure")
+
+func (f *fakeDB) CreateServer(ctx context.Context, arg database.CreateServerParams) (database.Server, error) {
+	if err := f.mutate("CreateServer"); err != nil {
+		return database.Server{}, err
+	}
+	return database.Server{ID: arg.ID, OwnerID: arg.OwnerID, ServerName: arg.ServerName, InviteCode: arg.InviteCode}, nil
+}
+
+func (f *fakeDB) CreateInvite(ctx context.Context, arg database.CreateInviteParams) (database.Invite, error) {
+	if err := f.mutate("CreateInvite"); err != nil {
+		return database.Invite{}, err
+	}
+	return database.Invite{Code: arg.Code, ServerID: arg.ServerID}, nil
+}
+
+func (f *fakeDB) CreateUserStandard(ctx context.Context, arg database.CreateUserStandardParams) (database.User, error) {
+	if err := f.mutate("CreateUserStandard"); err != nil {
+		return database.User{}, err
+	}
+	return database.User{ID: arg.ID, Email: arg.Email, Handle: arg.Handle}, nil
+}
+
+func (f *fakeDB) GetRoleIDByName(ctx context.Context, name string) (uuid.UUID, error) {
+	return uuid.New(), nil
+}
+
+func (f *fakeDB) CreateUserRoles(ctx context.Context, arg database.CreateUserRolesParams) (database.UserRole, error) {
+	if err := f.mutate("CreateUserRoles"); err != nil {
+		return database.UserRole{}, err
+	}
+	return database.UserRole{UserID: arg.UserID, RoleID: arg.RoleID}, nil
+}
+
+func (f *fakeDB) UsePasswordResetToken(ctx context.Context, arg database.UsePasswordResetTokenParams) (database.PasswordResetToken, error) {
+	if err := f.mutate("UsePasswordResetToken"); err != nil {
+		return database.PasswordResetToken{}, err
+	}
+	return database.PasswordResetToken{UserID: uuid.New()}, nil
+}
+
+func (f *fakeDB) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) (database.User, error) {
+	if err := f.mutate("UpdateUserPassword"); err != nil {
+		return database.User{}, err
+	}
+	return database.User{ID: arg.ID, Password: arg.Password}, nil
+}
+
+func (f *fakeDB) InvalidateUserPasswordResetTokens(ctx context.Context, arg database.InvalidateUserPasswordResetTokensParams) error {
+	return f.mutate("InvalidateUserPasswordResetTokens")
+}
+
+func (f *fakeDB) CreateBotUser(ctx context.Context, arg database.CreateBotUserParams) (database.User, error) {
+	if err := f.mutate("CreateBotUser"); err != nil {
+		return database.User{}, err
+	}
+	return database.User{ID: arg.ID, Handle: arg.Handle, IsBot: true, BotOwnerID: arg.BotOwnerID}, nil
+}
+
+func (f *fakeDB) CreatePersonalAccessToken(ctx context.Context, arg database.CreatePersonalAccessTokenParams) (database.PersonalAccessToken, error) {
+	if err := f.mutate("CreatePersonalAccessToken"); err != nil {
+		return database.PersonalAccessToken{}, err
+	}
+	return database.PersonalAccessToken{ID: arg.ID, UserID: arg.UserID}, nil
+}
+
+type txFlow struct {
+	name    string
+	handler func(h *handlers.Handlers) http.HandlerFunc
+	request func(f *fakeDB) *http.Request
+	// writes lists the writes the handler makes in one transaction, in order.
+	writes []string
+}
+
+var txFlows = []txFlow{
+	{
+		name:    "CreateServer",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateServer },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/servers", map[string]any{"server_name": "new server"})
+		},
+		writes: []string{"CreateServer", "CreateUserServer", "CreateServerRole", "CreateInvite"},
+	},
+	{
+		name:    "CreateUserStandard",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateUserStandard },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/users", map[string]any{
+				"email":    "new@example.com",
+				"handle":   "new",
+				"password": "password123",
+			})
+		},
+		writes: []string{"CreateUserStandard", "CreateUserRoles"},
+	},
+	{
+		name:    "ResetPassword",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.ResetPassword },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/password/reset", map[string]any{
+				"token":    "token",
+				"password": "password123",
+			})
+		},
+		writes: []string{"UsePasswordResetToken", "UpdateUserPassword", "InvalidateUserPasswordResetTokens"},
+	},
+	{
+		name:    "CreateBot",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.CreateBot },
+		request: func(f *fakeDB) *http.Request {
+			return jsonRequest(http.MethodPost, "/v1/bots", map[string]any{"handle": "helper"})
+		},
+		writes: []string{"CreateBotUser", "CreatePersonalAccessToken"},
+	},
+	{
+		name:    "KickMember",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.KickMember },
+		request: func(f *fakeDB) *http.Request {
+			target := f.target()
+			r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/members/"+target.String(), nil)
+			r.SetPathValue("serverID", f.serverID.String())
+			r.SetPathValue("userID", target.String())
+			return r
+		},
+		writes: []string{"RemoveServerMember", "LeaveServerVoiceChannels", "CreateAuditLogEntry"},
+	},
+	{
+		name:    "KickBooster",
+		handler: func(h *handlers.Handlers) http.HandlerFunc { return h.KickMember },
+		request: func(f *fakeDB) *http.Request {
+			target := f.target()
+			f.boosts[target] = true
+			r := httptest.NewRequest(http.MethodDelete, "/v1/servers/"+f.serverID.String()+"/members/"+target.String(), nil)
+			r.SetPathValue("serverID", f.serverID.String())
+			r.SetPathValue("userID", target.String())
+			return r
+		},
+		writes: []string{"RemoveServerMember", "LeaveServerVoiceChannels", "RemoveServerBoost", "CreateAuditLogEntry"},
+	},
+}
+
+// TestFailedWriteRollsBack fails each write of a multi-step handler in turn
+// and checks none of the earlier writes survive.
+func TestFailedWriteRollsBack(t *testing.T) {
+	for _, flow := range txFlows {
+		for _, failing := range flow.writes {
+			t.Run(flow.name+"/"+failing, func(t *testing.T) {
+				f := newFakeDB()
+				f.failOn = failing
+				h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+				owner := database.User{ID: f.ownerID}
+				f.members[owner.ID] = permissions.Default
+
+				r := flow.request(f)
+				r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, owner))
+				w := httptest.NewRecorder()
+
+				flow.handler(h)(w, r)
+
+				if !f.failed {
+					t.Fatalf("%s was never reached (status %d, body %q)", failing, w.Code, w.Body.String())
+				}
+				if w.Code != http.StatusInternalServerError {
+					t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusInternalServerError, w.Body.String())
+				}
+				if len(f.mutations) > 0 {
+					t.Fatalf("writes %v survived the failed transaction", f.mutations)
+				}
+			})
+		}
+	}
+}
+
+func TestCreateServerCommitsEveryWrite(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f}
+
+	user := database.User{ID: uuid.New()}
+	r := txFlows[0].request(f)
+	r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	h.CreateServer(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	if !slices.Equal(f.mutations, txFlows[0].writes) {
+		t.Fatalf("writes = %v, want %v", f.mutations, txFlows[0].writes)
+	}
+}
@@ -0,0 +1,288 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+func (f *fakeDB) GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error) {
+	if f.totp.UserID != userID {
+		return database.UserTotp{}, sql.ErrNoRows
+	}
+	return f.totp, nil
+}
+
+// UpdateUserTOTPLastUsedStep only moves the step forward, as the conditional
+// UPDATE does.
+func (f *fakeDB) UpdateUserTOTPLastUsedStep(ctx context.Context, arg database.UpdateUserTOTPLastUsedStepParams) (int64, error) {
+	if f.totp.UserID != arg.UserID || arg.LastUsedStep <= f.totp.LastUsedStep {
+		return 0, nil
+	}
+	f.totp.LastUsedStep = arg.LastUsedStep
+	return 1, nil
+}
+
+func (f *fakeDB) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	used, ok := f.recoveryCodes[arg.CodeHash]
+	if !ok || used || f.totp.UserID != arg.UserID {
+		return 0, nil
+	}
+	f.recoveryCodes[arg.CodeHash] = true
+	return 1, nil
+}
+
+func (f *fakeDB) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	if err := f.mutate("CreateSession"); err != nil {
+		return database.Session{}, err
+	}
+	return database.Session{ID: arg.ID, UserID: arg.UserID, CreatedAt: arg.CreatedAt, LastUsedAt: arg.LastUsedAt}, nil
+}
+
+// totpCode computes the RFC 6238 code for secret at the given time, apart from
+// the implementation under test.
+func totpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+type twoFactorFixture struct {
+	f     *fakeDB
+	cache *fakeCache
+	h     *handlers.Handlers
+	user  database.User
+}
+
+func newTwoFactorFixture(t *testing.T, limits handlers.LoginLimits) *twoFactorFixture {
+	t.Helper()
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fx := &twoFactorFixture{
+		f:     newFakeDB(),
+		cache: newFakeCache(),
+		user:  database.User{ID: uuid.New(), Email: "user@example.com", Handle: "user"},
+	}
+	fx.f.totp = database.UserTotp{
+		UserID:    fx.user.ID,
+		Secret:    secret,
+		EnabledAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	}
+	fx.h = &handlers.Handlers{
+		DB:          fx.f,
+		RDB:         fx.cache,
+		JWT:         newKeySet(t),
+		Ws:          websocket.NewManager(nil, nil),
+		LoginLimits: limits,
+	}
+	return fx
+}
+
+// challenge stores a login challenge for the fixture's user, as a correct
+// password would, and returns its token.
+func (fx *twoFactorFixture) challenge(t *testing.T) string {
+	t.Helper()
+
+	token := uuid.NewString()
+	err := fx.cache.SetJson("2fa"+token, map[string]any{
+		"user_id":    fx.user.ID,
+		"expires_at": time.Now().UTC().Add(5 * time.Minute),
+	}, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func (fx *twoFactorFixture) complete(token, code, recoveryCode string) *httptest.ResponseRecorder {
+	r := jsonRequest(http.MethodPost, "/v1/login/2fa", map[string]string{
+		"challenge_token": token,
+		"code":            code,
+		"recovery_code":   recoveryCode,
+	})
+	w := httptest.NewRecorder()
+
+	fx.h.CompleteTwoFactorLogin(w, r)
+	return w
+}
+
+func TestCompleteTwoFactorLogin(t *testing.T) {
+	fx := newTwoFactorFixture(t, handlers.DefaultLoginLimits())
+	token := fx.challenge(t)
+
+	w := fx.complete(token, totpCode(t, fx.f.totp.Secret, time.Now()), "")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+	if cookie(w, "access_token") == "" || cookie(w, "refresh_token") == "" {
+		t.Fatal("login did not set session cookies")
+	}
+
+	// The challenge is spent once it has been used.
+	w = fx.complete(token, totpCode(t, fx.f.totp.Secret, time.Now()), "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("reused challenge: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCompleteTwoFactorLoginRejectsReplayedCode(t *testing.T) {
+	fx := newTwoFactorFixture(t, handlers.DefaultLoginLimits())
+	code := totpCode(t, fx.f.totp.Secret, time.Now())
+
+	if w := fx.complete(fx.challenge(t), code, ""); w.Code != http.StatusOK {
+		t.Fatalf("first login: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w := fx.complete(fx.challenge(t), code, "")
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusUnauthorized, w.Body.String())
+	}
+	if cookie(w, "access_token") != "" {
+		t.Fatal("a replayed code started a session")
+	}
+}
+
+func TestCompleteTwoFactorLoginRecoveryCodeIsSingleUse(t *testing.T) {
+	fx := newTwoFactorFixture(t, handlers.DefaultLoginLimits())
+	fx.f.recoveryCodes[utils.HashToken("abcde12345")] = false
+
+	if w := fx.complete(fx.challenge(t), "", "ABCDE-12345"); w.Code != http.StatusOK {
+		t.Fatalf("first use: status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	if w := fx.complete(fx.challenge(t), "", "ABCDE-12345"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("second use: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+// TestCompleteTwoFactorLoginLimitsParallelGuesses sends more guesses at once
+// than a challenge allows. Only the allowed number may be checked, after
+// which the challenge is gone even for the right code.
+func TestCompleteTwoFactorLoginLimitsParallelGuesses(t *testing.T) {
+	// Keep the per-user and IP limits out of the way of the challenge's own.
+	fx := newTwoFactorFixture(t, handlers.LoginLimits{
+		FreeAttempts:    100,
+		MaxAttempts:     101,
+		IPMaxAttempts:   101,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	})
+	token := fx.challenge(t)
+
+	var mu sync.Mutex
+	checked := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := fx.complete(token, "000000", "")
+			if strings.Contains(w.Body.String(), "Invalid code") {
+				mu.Lock()
+				checked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked != 5 {
+		t.Fatalf("checked %d guesses, want 5", checked)
+	}
+
+	w := fx.complete(token, totpCode(t, fx.f.totp.Secret, time.Now()), "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("right code after the limit: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestCompleteTwoFactorLoginBacksOffWrongCodes(t *testing.T) {
+	limits := handlers.DefaultLoginLimits()
+	fx := newTwoFactorFixture(t, limits)
+
+	for i := int64(0); i < limits.FreeAttempts; i++ {
+		w := fx.complete(fx.challenge(t), "000000", "")
+		if w.Code != http.StatusUnauthorized || w.Header().Get("Retry-After") != "" {
+			t.Fatalf("free attempt %d: status = %d, Retry-After %q", i+1, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+
+	w := fx.complete(fx.challenge(t), "000000", "")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("first delayed attempt: status = %d, Retry-After %q, want 401 and 1", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = fx.complete(fx.challenge(t), totpCode(t, fx.f.totp.Secret, time.Now()), "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("attempt during backoff: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestDisableTwoFactorRequiresCode(t *testing.T) {
+	limits := handlers.DefaultLoginLimits()
+	fx := newTwoFactorFixture(t, limits)
+
+	disable := func(code string) *httptest.ResponseRecorder {
+		r := jsonRequest(http.MethodDelete, "/v1/users/2fa", map[string]string{"code": code})
+		r = r.WithContext(context.WithValue(r.Context(), common.UserContextKey, fx.user))
+		w := httptest.NewRecorder()
+		fx.h.DisableTwoFactor(w, r)
+		return w
+	}
+
+	for i := int64(0); i < limits.MaxAttempts; i++ {
+		if w := disable("000000"); w.Code != http.StatusForbidden {
+			t.Fatalf("wrong code %d: status = %d, want %d", i+1, w.Code, http.StatusForbidden)
+		}
+		// Skip the backoff between guesses to reach the lockout.
+		if i < limits.MaxAttempts-1 {
+			fx.cache.Delete("loginblock:user:" + fx.user.ID.String())
+		}
+	}
+
+	w := disable("000000")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after %d wrong codes: status = %d, want %d", limits.MaxAttempts, w.Code, http.StatusTooManyRequests)
+	}
+	if len(fx.f.mutations) > 0 {
+		t.Fatalf("writes %v were made without a valid code", fx.f.mutations)
+	}
+}
@@ -1,5 +1,13 @@
 package handlers_test
 
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+)
+
 // import (
 //     "bytes"
 //     "context"
@@ -67,4 +75,26 @@ package handlers_test
 //     assert.Equal(t, expectedUser.Handle, response.Handle)
 
 //     mockDB.AssertExpectations(t)
-// }
\ No newline at end of file
+// }
+func TestCreateUserRejectsInvalidEmail(t *testing.T) {
+	for _, email := range []string{"", "not-an-email", "Name <name@example.com>", "../../x"} {
+		f := newFakeDB()
+		h := &handlers.Handlers{DB: f}
+
+		r := jsonRequest(http.MethodPost, "/v1/users", map[string]any{
+			"email":    email,
+			"handle":   "new",
+			"password": "password123",
+		})
+		w := httptest.NewRecorder()
+
+		h.CreateUserStandard(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("email %q: status = %d, want %d", email, w.Code, http.StatusBadRequest)
+		}
+		if len(f.mutations) > 0 {
+			t.Fatalf("email %q: writes %v were made", email, f.mutations)
+		}
+	}
+}
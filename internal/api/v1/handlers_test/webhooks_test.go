@@ -0,0 +1,191 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/utils"
+)
+
+const webhookToken = "secret"
+
+func (f *fakeDB) CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error) {
+	if err := f.mutate("CreateWebhook"); err != nil {
+		return database.Webhook{}, err
+	}
+	return database.Webhook{ID: arg.ID, ChannelID: arg.ChannelID, CreatorID: arg.CreatorID, Name: arg.Name, TokenHash: arg.TokenHash}, nil
+}
+
+// GetWebhookByToken knows one webhook on the fake channel, named "CI".
+func (f *fakeDB) GetWebhookByToken(ctx context.Context, arg database.GetWebhookByTokenParams) (database.GetWebhookByTokenRow, error) {
+	if arg.ID != f.webhookID || arg.TokenHash != utils.HashToken(webhookToken) {
+		return database.GetWebhookByTokenRow{}, sql.ErrNoRows
+	}
+	return database.GetWebhookByTokenRow{
+		ID:        f.webhookID,
+		ChannelID: f.channel.ID,
+		CreatorID: f.ownerID,
+		Name:      "CI",
+		ServerID:  f.serverID,
+	}, nil
+}
+
+func (f *fakeDB) CreateWebhookMessage(ctx context.Context, arg database.CreateWebhookMessageParams) (database.TextMessage, error) {
+	if err := f.mutate("CreateWebhookMessage"); err != nil {
+		return database.TextMessage{}, err
+	}
+	return database.TextMessage{
+		ID:           arg.ID,
+		OwnerID:      arg.OwnerID,
+		ChannelID:    arg.ChannelID,
+		Message:      arg.Message,
+		WebhookID:    arg.WebhookID,
+		AuthorName:   arg.AuthorName,
+		AuthorAvatar: arg.AuthorAvatar,
+	}, nil
+}
+
+func executeWebhook(t *testing.T, f *fakeDB, webhookID, token string, body map[string]any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	h := &handlers.Handlers{DB: f, Ws: websocket.NewManager(nil, nil)}
+
+	r := jsonRequest(http.MethodPost, "/v1/webhooks/"+webhookID+"/"+token, body)
+	r.SetPathValue("webhookID", webhookID)
+	r.SetPathValue("token", token)
+	w := httptest.NewRecorder()
+
+	h.ExecuteWebhook(w, r)
+	return w
+}
+
+func TestExecuteWebhookPostsMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       map[string]any
+		wantHandle string
+	}{
+		{"webhook name", map[string]any{"message": "build passed"}, "CI"},
+		{"username override", map[string]any{"message": "build passed", "username": "Deploy bot"}, "Deploy bot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDB()
+			f.webhookID = uuid.New()
+
+			w := executeWebhook(t, f, f.webhookID.String(), webhookToken, tt.body)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusCreated, w.Body.String())
+			}
+
+			var message handlers.SimpleMessage
+			if err := json.NewDecoder(w.Body).Decode(&message); err != nil {
+				t.Fatal(err)
+			}
+			if message.OwnerHandle != tt.wantHandle {
+				t.Fatalf("handle = %q, want %q", message.OwnerHandle, tt.wantHandle)
+			}
+			if message.WebhookID == nil || *message.WebhookID != f.webhookID {
+				t.Fatalf("webhook_id = %v, want %s", message.WebhookID, f.webhookID)
+			}
+			if !message.IsBot {
+				t.Fatal("webhook message is not marked as a bot message")
+			}
+			if message.ChannelID != f.channel.ID {
+				t.Fatalf("channel = %s, want %s", message.ChannelID, f.channel.ID)
+			}
+		})
+	}
+}
+
+func TestExecuteWebhookRejectsBadCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		webhookID func(f *fakeDB) string
+		token     string
+	}{
+		{"wrong token", func(f *fakeDB) string { return f.webhookID.String() }, "guess"},
+		{"unknown webhook", func(f *fakeDB) string { return uuid.NewString() }, webhookToken},
+		{"malformed id", func(f *fakeDB) string { return "not-a-uuid" }, webhookToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDB()
+			f.webhookID = uuid.New()
+
+			w := executeWebhook(t, f, tt.webhookID(f), tt.token, map[string]any{"message": "hello"})
+
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusNotFound, w.Body.String())
+			}
+			if len(f.mutations) > 0 {
+				t.Fatalf("rejected webhook reached %v", f.mutations)
+			}
+		})
+	}
+}
+
+func TestExecuteWebhookRequiresMessage(t *testing.T) {
+	f := newFakeDB()
+	f.webhookID = uuid.New()
+
+	w := executeWebhook(t, f, f.webhookID.String(), webhookToken, map[string]any{"username": "CI"})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+}
+
+// GetChannelTextMessages returns one message posted through a webhook that
+// has since been deleted, so its webhook_id is NULL.
+func (f *fakeDB) GetChannelTextMessages(ctx context.Context, channelID uuid.UUID) ([]database.GetChannelTextMessagesRow, error) {
+	return []database.GetChannelTextMessagesRow{{
+		ID:           uuid.New(),
+		OwnerID:      f.ownerID,
+		ChannelID:    channelID,
+		Message:      "build passed",
+		AuthorName:   sql.NullString{String: "CI", Valid: true},
+		AuthorAvatar: sql.NullString{String: "https://example.com/ci.png", Valid: true},
+		Handle:       "owner",
+	}}, nil
+}
+
+func TestDeletedWebhookMessagesKeepAuthor(t *testing.T) {
+	f := newFakeDB()
+	h := &handlers.Handlers{DB: f}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/messages/"+f.channel.ID.String(), nil)
+	w := httptest.NewRecorder()
+
+	h.GetChannelTextMessages(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %q)", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var messages []handlers.SimpleMessage
+	if err := json.NewDecoder(w.Body).Decode(&messages); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	message := messages[0]
+	if message.OwnerHandle != "CI" || message.OwnerImage != "https://example.com/ci.png" || !message.IsBot {
+		t.Fatalf("message shows %q %q bot=%v, want the webhook's name and avatar", message.OwnerHandle, message.OwnerImage, message.IsBot)
+	}
+	if message.WebhookID != nil {
+		t.Fatalf("webhook ID = %v, want none once the webhook is deleted", *message.WebhookID)
+	}
+}
@@ -6,16 +6,96 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID         uuid.UUID       `json:"id"`
+	ServerID   uuid.UUID       `json:"server_id"`
+	ActorID    uuid.NullUUID   `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   uuid.NullUUID   `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Reason     sql.NullString  `json:"reason"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type EmailVerificationToken struct {
+	TokenHash string       `json:"token_hash"`
+	UserID    uuid.UUID    `json:"user_id"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+	Email     string       `json:"email"`
+}
+
+type Invite struct {
+	Code      string        `json:"code"`
+	ServerID  uuid.UUID     `json:"server_id"`
+	CreatorID uuid.NullUUID `json:"creator_id"`
+	MaxUses   sql.NullInt32 `json:"max_uses"`
+	Uses      int32         `json:"uses"`
+	ExpiresAt sql.NullTime  `json:"expires_at"`
+	RevokedAt sql.NullTime  `json:"revoked_at"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type JoinRequest struct {
+	ID        uuid.UUID      `json:"id"`
+	ServerID  uuid.UUID      `json:"server_id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Message   sql.NullString `json:"message"`
+	Status    string         `json:"status"`
+	DecidedBy uuid.NullUUID  `json:"decided_by"`
+	DecidedAt sql.NullTime   `json:"decided_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
 type Language struct {
 	ID       uuid.UUID `json:"id"`
 	Language string    `json:"language"`
 }
 
+type MemberRole struct {
+	UserID   uuid.UUID `json:"user_id"`
+	ServerID uuid.UUID `json:"server_id"`
+	RoleID   uuid.UUID `json:"role_id"`
+}
+
+type PasswordResetToken struct {
+	TokenHash string       `json:"token_hash"`
+	UserID    uuid.UUID    `json:"user_id"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type RefreshToken struct {
+	ID         uuid.UUID     `json:"id"`
+	FamilyID   uuid.UUID     `json:"family_id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	RevokedAt  sql.NullTime  `json:"revoked_at"`
+	ReplacedBy uuid.NullUUID `json:"replaced_by"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
 type Role struct {
 	ID   uuid.UUID `json:"id"`
 	Name string    `json:"name"`
@@ -35,6 +115,57 @@ type Server struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	InviteCode  string         `json:"invite_code"`
+	BoostCount  int32          `json:"boost_count"`
+}
+
+type ServerBan struct {
+	ServerID  uuid.UUID      `json:"server_id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Reason    sql.NullString `json:"reason"`
+	BannedBy  uuid.NullUUID  `json:"banned_by"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type ServerBoost struct {
+	ServerID  uuid.UUID `json:"server_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ServerRole struct {
+	ID          uuid.UUID `json:"id"`
+	ServerID    uuid.UUID `json:"server_id"`
+	Name        string    `json:"name"`
+	Permissions int64     `json:"permissions"`
+	Position    int32     `json:"position"`
+	IsDefault   bool      `json:"is_default"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ServerTag struct {
+	ServerID uuid.UUID `json:"server_id"`
+	Tag      string    `json:"tag"`
+}
+
+type Session struct {
+	ID         uuid.UUID      `json:"id"`
+	UserID     uuid.UUID      `json:"user_id"`
+	UserAgent  sql.NullString `json:"user_agent"`
+	IpAddress  sql.NullString `json:"ip_address"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastUsedAt time.Time      `json:"last_used_at"`
+	RevokedAt  sql.NullTime   `json:"revoked_at"`
+}
+
+type SigningKey struct {
+	ID         string       `json:"id"`
+	Algorithm  string       `json:"algorithm"`
+	PrivateKey []byte       `json:"private_key"`
+	CreatedAt  time.Time    `json:"created_at"`
+	RetiredAt  sql.NullTime `json:"retired_at"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
 }
 
 type TextChannel struct {
@@ -50,13 +181,16 @@ type TextChannel struct {
 }
 
 type TextMessage struct {
-	ID        uuid.UUID      `json:"id"`
-	OwnerID   uuid.UUID      `json:"owner_id"`
-	ChannelID uuid.UUID      `json:"channel_id"`
-	Message   string         `json:"message"`
-	Image     sql.NullString `json:"image"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	ID           uuid.UUID      `json:"id"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	ChannelID    uuid.UUID      `json:"channel_id"`
+	Message      string         `json:"message"`
+	Image        sql.NullString `json:"image"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	WebhookID    uuid.NullUUID  `json:"webhook_id"`
+	AuthorName   sql.NullString `json:"author_name"`
+	AuthorAvatar sql.NullString `json:"author_avatar"`
 }
 
 type User struct {
@@ -72,6 +206,26 @@ type User struct {
 	IsVerified sql.NullBool   `json:"is_verified"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
+	IsBot      bool           `json:"is_bot"`
+	BotOwnerID uuid.NullUUID  `json:"bot_owner_id"`
+}
+
+type UserIdentity struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Provider    string         `json:"provider"`
+	Subject     string         `json:"subject"`
+	Email       sql.NullString `json:"email"`
+	CreatedAt   time.Time      `json:"created_at"`
+	LastLoginAt time.Time      `json:"last_login_at"`
+}
+
+type UserRecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 type UserRole struct {
@@ -80,9 +234,18 @@ type UserRole struct {
 }
 
 type UserServer struct {
-	UserID   uuid.UUID `json:"user_id"`
-	ServerID uuid.UUID `json:"server_id"`
-	Role     string    `json:"role"`
+	UserID     uuid.UUID      `json:"user_id"`
+	ServerID   uuid.UUID      `json:"server_id"`
+	InviteCode sql.NullString `json:"invite_code"`
+	MutedUntil sql.NullTime   `json:"muted_until"`
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	Secret       string       `json:"secret"`
+	EnabledAt    sql.NullTime `json:"enabled_at"`
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    time.Time    `json:"created_at"`
 }
 
 type VoiceChannel struct {
@@ -102,3 +265,14 @@ type VoiceChannelMember struct {
 	ChannelID uuid.UUID `json:"channel_id"`
 	ServerID  uuid.UUID `json:"server_id"`
 }
+
+type Webhook struct {
+	ID        uuid.UUID      `json:"id"`
+	ChannelID uuid.UUID      `json:"channel_id"`
+	CreatorID uuid.UUID      `json:"creator_id"`
+	Name      string         `json:"name"`
+	AvatarUrl sql.NullString `json:"avatar_url"`
+	TokenHash string         `json:"token_hash"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	queryName   = regexp.MustCompile(`^-- name: (\w+) :\w+`)
+	queryParams = regexp.MustCompile(`sqlc\.n?arg\(\w+\)|sqlc\.slice\(\w+\)|@\w+|\$\d+`)
+	queryStar   = regexp.MustCompile(`(\w+\.)?\*`)
+)
+
+// TestQueriesMatchSource guards against generated queries drifting from
+// sql/queries. sqlc rewrites parameters and expands stars in place, so each
+// line of a query's body still lines up with a line of its source.
+func TestQueriesMatchSource(t *testing.T) {
+	generated := generatedQueries(t)
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "sql", "queries", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		for name, source := range sourceQueries(t, file) {
+			query, ok := generated[name]
+			if !ok {
+				t.Errorf("%s: %s has no generated query", filepath.Base(file), name)
+				continue
+			}
+
+			want := normalizeQuery(source)
+			got := normalizeQuery(query)
+			if len(got) != len(want) {
+				t.Errorf("%s: generated query has %d lines, source has %d", name, len(got), len(want))
+				continue
+			}
+			for i := range want {
+				if got[i] != want[i] && !starExpansion(want[i], got[i]) {
+					t.Errorf("%s line %d: generated %q, source %q", name, i+1, got[i], want[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+// generatedQueries collects the query constants from the generated files,
+// keyed by query name.
+func generatedQueries(t *testing.T) map[string]string {
+	t.Helper()
+
+	files, err := filepath.Glob("*.sql.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return true
+			}
+			if m := queryName.FindStringSubmatch(value); m != nil {
+				queries[m[1]] = value
+			}
+			return true
+		})
+	}
+	return queries
+}
+
+// sourceQueries splits a query file into its named queries.
+func sourceQueries(t *testing.T, file string) map[string]string {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := make(map[string]string)
+	var name string
+	var body strings.Builder
+	flush := func() {
+		if name != "" {
+			queries[name] = body.String()
+		}
+		body.Reset()
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := queryName.FindStringSubmatch(line); m != nil {
+			flush()
+			name = m[1]
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return queries
+}
+
+// normalizeQuery drops the name line and the comments sqlc moves into the
+// doc comment, and replaces parameters with a placeholder.
+func normalizeQuery(query string) []string {
+	var lines []string
+	for _, line := range strings.Split(query, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		line = strings.TrimSuffix(line, ";")
+		lines = append(lines, queryParams.ReplaceAllString(line, "?"))
+	}
+	return lines
+}
+
+// starExpansion reports whether got is want with its star expanded into a
+// column list.
+func starExpansion(want, got string) bool {
+	loc := queryStar.FindStringIndex(want)
+	if loc == nil || strings.Contains(want, "(*)") {
+		return false
+	}
+	return strings.HasPrefix(got, want[:loc[0]]) && strings.HasSuffix(got, want[loc[1]:])
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createServer = `-- name: CreateServer :one
@@ -23,7 +24,7 @@ INSERT INTO servers (
         updated_at
     )
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code
+RETURNING id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
 `
 
 type CreateServerParams struct {
@@ -59,6 +60,7 @@ func (q *Queries) CreateServer(ctx context.Context, arg CreateServerParams) (Ser
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InviteCode,
+		&i.BoostCount,
 	)
 	return i, err
 }
@@ -73,8 +75,185 @@ func (q *Queries) DeleteServer(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const discoverServers = `-- name: DiscoverServers :many
+WITH candidates AS (
+    SELECT s.id,
+        s.server_name,
+        s.description,
+        s.icon_url,
+        s.banner_url,
+        s.member_count,
+        s.created_at,
+        s.updated_at,
+        u.handle,
+        u.avatar_url,
+        (
+            CASE
+                $1::TEXT
+                WHEN 'members' THEN COALESCE(s.member_count, 0)
+                WHEN 'activity' THEN EXTRACT(
+                    EPOCH
+                    FROM COALESCE(
+                            (
+                                SELECT MAX(tm.created_at)
+                                FROM text_messages tm
+                                    JOIN text_channels tc ON tc.id = tm.channel_id
+                                WHERE tc.server_id = s.id
+                            ),
+                            s.created_at
+                        )
+                )
+                ELSE EXTRACT(
+                    EPOCH
+                    FROM s.created_at
+                )
+            END
+        )::DOUBLE PRECISION AS sort_key,
+        COALESCE(
+            (
+                SELECT array_agg(
+                        t.tag
+                        ORDER BY t.tag
+                    )
+                FROM server_tags t
+                WHERE t.server_id = s.id
+            ),
+            '{}'
+        )::TEXT [] AS tags
+    FROM servers s
+        INNER JOIN users u ON s.owner_id = u.id
+    WHERE s.is_public = TRUE
+        AND (
+            $2::TEXT = ''
+            OR to_tsvector(
+                'simple',
+                s.server_name || ' ' || COALESCE(s.description, '')
+            ) @@ websearch_to_tsquery('simple', $2::TEXT)
+        )
+        AND (
+            $3::TEXT = ''
+            OR EXISTS (
+                SELECT 1
+                FROM server_tags t
+                WHERE t.server_id = s.id
+                    AND t.tag = $3::TEXT
+            )
+        )
+        AND (
+            $4::TEXT = ''
+            OR EXISTS (
+                SELECT 1
+                FROM text_channels tc
+                    JOIN languages l ON l.id = tc.language_id
+                WHERE tc.server_id = s.id
+                    AND l.language = $4::TEXT
+            )
+            OR EXISTS (
+                SELECT 1
+                FROM voice_channels vc
+                    JOIN languages l ON l.id = vc.language_id
+                WHERE vc.server_id = s.id
+                    AND l.language = $4::TEXT
+            )
+        )
+)
+SELECT id,
+    server_name,
+    description,
+    icon_url,
+    banner_url,
+    member_count,
+    created_at,
+    updated_at,
+    handle,
+    avatar_url,
+    sort_key,
+    tags
+FROM candidates
+WHERE $5::DOUBLE PRECISION IS NULL
+    OR (sort_key, id) < (
+        $5::DOUBLE PRECISION,
+        $6::UUID
+    )
+ORDER BY sort_key DESC,
+    id DESC
+LIMIT $7
+`
+
+type DiscoverServersParams struct {
+	Sort     string          `json:"sort"`
+	Query    string          `json:"query"`
+	Tag      string          `json:"tag"`
+	Language string          `json:"language"`
+	AfterKey sql.NullFloat64 `json:"after_key"`
+	AfterID  uuid.UUID       `json:"after_id"`
+	PageSize int32           `json:"page_size"`
+}
+
+type DiscoverServersRow struct {
+	ID          uuid.UUID      `json:"id"`
+	ServerName  string         `json:"server_name"`
+	Description sql.NullString `json:"description"`
+	IconUrl     sql.NullString `json:"icon_url"`
+	BannerUrl   sql.NullString `json:"banner_url"`
+	MemberCount sql.NullInt32  `json:"member_count"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	Handle      string         `json:"handle"`
+	AvatarUrl   sql.NullString `json:"avatar_url"`
+	SortKey     float64        `json:"sort_key"`
+	Tags        []string       `json:"tags"`
+}
+
+// Public servers matching the optional search, tag and channel language,
+// ordered by the chosen sort key and then id. Pages continue after the
+// previous page's last key and id.
+func (q *Queries) DiscoverServers(ctx context.Context, arg DiscoverServersParams) ([]DiscoverServersRow, error) {
+	rows, err := q.db.QueryContext(ctx, discoverServers,
+		arg.Sort,
+		arg.Query,
+		arg.Tag,
+		arg.Language,
+		arg.AfterKey,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DiscoverServersRow
+	for rows.Next() {
+		var i DiscoverServersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServerName,
+			&i.Description,
+			&i.IconUrl,
+			&i.BannerUrl,
+			&i.MemberCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Handle,
+			&i.AvatarUrl,
+			&i.SortKey,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getOneServerByCode = `-- name: GetOneServerByCode :one
-SELECT id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code
+SELECT id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
 FROM servers
 WHERE invite_code = $1
 `
@@ -96,12 +275,13 @@ func (q *Queries) GetOneServerByCode(ctx context.Context, inviteCode string) (Se
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InviteCode,
+		&i.BoostCount,
 	)
 	return i, err
 }
 
 const getOneServerByID = `-- name: GetOneServerByID :one
-SELECT id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code
+SELECT id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
 FROM servers
 WHERE id = $1
 `
@@ -123,6 +303,7 @@ func (q *Queries) GetOneServerByID(ctx context.Context, id uuid.UUID) (Server, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InviteCode,
+		&i.BoostCount,
 	)
 	return i, err
 }
@@ -192,6 +373,180 @@ func (q *Queries) GetRecentServers(ctx context.Context) ([]GetRecentServersRow,
 	return items, nil
 }
 
+const reconcileServerCounts = `-- name: ReconcileServerCounts :many
+WITH actual AS (
+    SELECT s.id,
+        s.member_count AS stored_members,
+        s.boost_count AS stored_boosts,
+        (
+            SELECT COUNT(*)
+            FROM user_servers us
+            WHERE us.server_id = s.id
+        )::INT AS members,
+        (
+            SELECT COUNT(*)
+            FROM server_boosts b
+            WHERE b.server_id = s.id
+        )::INT AS boosts
+    FROM servers s
+)
+UPDATE servers
+SET member_count = actual.members,
+    boost_count = actual.boosts,
+    server_level = (
+        SELECT COUNT(*)::INT
+        FROM unnest($1::INT []) AS threshold
+        WHERE threshold <= actual.boosts
+    ),
+    max_members = ($2::INT []) [(
+        SELECT COUNT(*)::INT
+        FROM unnest($1::INT []) AS threshold
+        WHERE threshold <= actual.boosts
+    ) + 1]
+FROM actual
+WHERE servers.id = actual.id
+    AND (
+        servers.member_count IS DISTINCT FROM actual.members
+        OR servers.boost_count <> actual.boosts
+    )
+RETURNING servers.id,
+    servers.server_name,
+    actual.stored_members,
+    actual.members,
+    actual.stored_boosts,
+    actual.boosts
+`
+
+type ReconcileServerCountsParams struct {
+	LevelThresholds []int32 `json:"level_thresholds"`
+	LevelMaxMembers []int32 `json:"level_max_members"`
+}
+
+type ReconcileServerCountsRow struct {
+	ID            uuid.UUID     `json:"id"`
+	ServerName    string        `json:"server_name"`
+	StoredMembers sql.NullInt32 `json:"stored_members"`
+	Members       int32         `json:"members"`
+	StoredBoosts  int32         `json:"stored_boosts"`
+	Boosts        int32         `json:"boosts"`
+}
+
+// Recounts every server's members and boosts from user_servers and
+// server_boosts and repairs the servers whose stored counts drifted. Their
+// level and cap follow the recounted boosts, as in BoostServer.
+func (q *Queries) ReconcileServerCounts(ctx context.Context, arg ReconcileServerCountsParams) ([]ReconcileServerCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, reconcileServerCounts, pq.Array(arg.LevelThresholds), pq.Array(arg.LevelMaxMembers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReconcileServerCountsRow
+	for rows.Next() {
+		var i ReconcileServerCountsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServerName,
+			&i.StoredMembers,
+			&i.Members,
+			&i.StoredBoosts,
+			&i.Boosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const transferServerOwnership = `-- name: TransferServerOwnership :one
+WITH transferred AS (
+    UPDATE servers
+    SET owner_id = $1,
+        updated_at = $2
+    WHERE servers.id = $3
+        AND servers.owner_id = $4
+        AND EXISTS (
+            SELECT 1
+            FROM user_servers
+            WHERE user_servers.user_id = $1
+                AND user_servers.server_id = $3
+        )
+    RETURNING id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
+),
+demoted AS (
+    INSERT INTO member_roles (user_id, server_id, role_id)
+    SELECT $4,
+        r.server_id,
+        r.id
+    FROM server_roles r
+        JOIN transferred t ON t.id = r.server_id
+    WHERE r.is_default = FALSE
+    ORDER BY r.position DESC
+    LIMIT 1 ON CONFLICT DO NOTHING
+)
+SELECT id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
+FROM transferred
+`
+
+type TransferServerOwnershipParams struct {
+	NewOwnerID uuid.UUID `json:"new_owner_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ID         uuid.UUID `json:"id"`
+	OwnerID    uuid.UUID `json:"owner_id"`
+}
+
+type TransferServerOwnershipRow struct {
+	ID          uuid.UUID      `json:"id"`
+	OwnerID     uuid.UUID      `json:"owner_id"`
+	ServerName  string         `json:"server_name"`
+	Description sql.NullString `json:"description"`
+	IconUrl     sql.NullString `json:"icon_url"`
+	BannerUrl   sql.NullString `json:"banner_url"`
+	IsPublic    sql.NullBool   `json:"is_public"`
+	MemberCount sql.NullInt32  `json:"member_count"`
+	ServerLevel sql.NullInt32  `json:"server_level"`
+	MaxMembers  sql.NullInt32  `json:"max_members"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	InviteCode  string         `json:"invite_code"`
+	BoostCount  int32          `json:"boost_count"`
+}
+
+// Hands the server to an existing member and gives the previous owner the
+// highest non-default role, so both changes land or neither does.
+func (q *Queries) TransferServerOwnership(ctx context.Context, arg TransferServerOwnershipParams) (TransferServerOwnershipRow, error) {
+	row := q.db.QueryRowContext(ctx, transferServerOwnership,
+		arg.NewOwnerID,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.OwnerID,
+	)
+	var i TransferServerOwnershipRow
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.ServerName,
+		&i.Description,
+		&i.IconUrl,
+		&i.BannerUrl,
+		&i.IsPublic,
+		&i.MemberCount,
+		&i.ServerLevel,
+		&i.MaxMembers,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InviteCode,
+		&i.BoostCount,
+	)
+	return i, err
+}
+
 const updateServerBannerByID = `-- name: UpdateServerBannerByID :one
 UPDATE servers
 SET banner_url = $2
@@ -225,7 +580,7 @@ SET server_name = $1,
     description = $2,
     updated_at = $3
 WHERE id = $4
-RETURNING id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code
+RETURNING id, owner_id, server_name, description, icon_url, banner_url, is_public, member_count, server_level, max_members, created_at, updated_at, invite_code, boost_count
 `
 
 type UpdateServerByIDParams struct {
@@ -257,6 +612,7 @@ func (q *Queries) UpdateServerByID(ctx context.Context, arg UpdateServerByIDPara
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.InviteCode,
+		&i.BoostCount,
 	)
 	return i, err
 }
@@ -287,30 +643,3 @@ func (q *Queries) UpdateServerIconByID(ctx context.Context, arg UpdateServerIcon
 	err := row.Scan(&i.ID, &i.ServerName, &i.IconUrl)
 	return i, err
 }
-
-const updateServerMemberCount = `-- name: UpdateServerMemberCount :one
-UPDATE servers
-SET member_count = $2
-WHERE id = $1
-RETURNING id,
-    server_name,
-    member_count
-`
-
-type UpdateServerMemberCountParams struct {
-	ID          uuid.UUID     `json:"id"`
-	MemberCount sql.NullInt32 `json:"member_count"`
-}
-
-type UpdateServerMemberCountRow struct {
-	ID          uuid.UUID     `json:"id"`
-	ServerName  string        `json:"server_name"`
-	MemberCount sql.NullInt32 `json:"member_count"`
-}
-
-func (q *Queries) UpdateServerMemberCount(ctx context.Context, arg UpdateServerMemberCountParams) (UpdateServerMemberCountRow, error) {
-	row := q.db.QueryRowContext(ctx, updateServerMemberCount, arg.ID, arg.MemberCount)
-	var i UpdateServerMemberCountRow
-	err := row.Scan(&i.ID, &i.ServerName, &i.MemberCount)
-	return i, err
-}
@@ -108,3 +108,25 @@ func (q *Queries) GetServerTextChannels(ctx context.Context, serverID uuid.UUID)
 	}
 	return items, nil
 }
+
+const getTextChannelByID = `-- name: GetTextChannelByID :one
+SELECT id, owner_id, server_id, language_id, channel_name, last_active, is_locked, created_at, updated_at FROM text_channels
+WHERE id = $1
+`
+
+func (q *Queries) GetTextChannelByID(ctx context.Context, id uuid.UUID) (TextChannel, error) {
+	row := q.db.QueryRowContext(ctx, getTextChannelByID, id)
+	var i TextChannel
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.ServerID,
+		&i.LanguageID,
+		&i.ChannelName,
+		&i.LastActive,
+		&i.IsLocked,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
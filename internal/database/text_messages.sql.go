@@ -24,7 +24,7 @@ INSERT INTO text_messages (
         updated_at
     )
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, owner_id, channel_id, message, image, created_at, updated_at
+RETURNING id, owner_id, channel_id, message, image, created_at, updated_at, webhook_id, author_name, author_avatar
 `
 
 type CreateTextMessageParams struct {
@@ -56,6 +56,68 @@ func (q *Queries) CreateTextMessage(ctx context.Context, arg CreateTextMessagePa
 		&i.Image,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.WebhookID,
+		&i.AuthorName,
+		&i.AuthorAvatar,
+	)
+	return i, err
+}
+
+const createWebhookMessage = `-- name: CreateWebhookMessage :one
+INSERT INTO text_messages (
+        id,
+        owner_id,
+        channel_id,
+        message,
+        image,
+        webhook_id,
+        author_name,
+        author_avatar,
+        created_at,
+        updated_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, owner_id, channel_id, message, image, created_at, updated_at, webhook_id, author_name, author_avatar
+`
+
+type CreateWebhookMessageParams struct {
+	ID           uuid.UUID      `json:"id"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	ChannelID    uuid.UUID      `json:"channel_id"`
+	Message      string         `json:"message"`
+	Image        sql.NullString `json:"image"`
+	WebhookID    uuid.NullUUID  `json:"webhook_id"`
+	AuthorName   sql.NullString `json:"author_name"`
+	AuthorAvatar sql.NullString `json:"author_avatar"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) CreateWebhookMessage(ctx context.Context, arg CreateWebhookMessageParams) (TextMessage, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookMessage,
+		arg.ID,
+		arg.OwnerID,
+		arg.ChannelID,
+		arg.Message,
+		arg.Image,
+		arg.WebhookID,
+		arg.AuthorName,
+		arg.AuthorAvatar,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i TextMessage
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.ChannelID,
+		&i.Message,
+		&i.Image,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.WebhookID,
+		&i.AuthorName,
+		&i.AuthorAvatar,
 	)
 	return i, err
 }
@@ -68,23 +130,31 @@ SELECT t.id,
     t.image,
     t.created_at,
     t.updated_at,
+    t.webhook_id,
+    t.author_name,
+    t.author_avatar,
     u.handle,
-    u.avatar_url
+    u.avatar_url,
+    u.is_bot
 FROM text_messages t
     INNER JOIN users u ON t.owner_id = u.id
 WHERE t.channel_id = $1
 `
 
 type GetChannelTextMessagesRow struct {
-	ID        uuid.UUID      `json:"id"`
-	OwnerID   uuid.UUID      `json:"owner_id"`
-	ChannelID uuid.UUID      `json:"channel_id"`
-	Message   string         `json:"message"`
-	Image     sql.NullString `json:"image"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	Handle    string         `json:"handle"`
-	AvatarUrl sql.NullString `json:"avatar_url"`
+	ID           uuid.UUID      `json:"id"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	ChannelID    uuid.UUID      `json:"channel_id"`
+	Message      string         `json:"message"`
+	Image        sql.NullString `json:"image"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	WebhookID    uuid.NullUUID  `json:"webhook_id"`
+	AuthorName   sql.NullString `json:"author_name"`
+	AuthorAvatar sql.NullString `json:"author_avatar"`
+	Handle       string         `json:"handle"`
+	AvatarUrl    sql.NullString `json:"avatar_url"`
+	IsBot        bool           `json:"is_bot"`
 }
 
 func (q *Queries) GetChannelTextMessages(ctx context.Context, channelID uuid.UUID) ([]GetChannelTextMessagesRow, error) {
@@ -104,8 +174,12 @@ func (q *Queries) GetChannelTextMessages(ctx context.Context, channelID uuid.UUI
 			&i.Image,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.WebhookID,
+			&i.AuthorName,
+			&i.AuthorAvatar,
 			&i.Handle,
 			&i.AvatarUrl,
+			&i.IsBot,
 		); err != nil {
 			return nil, err
 		}
@@ -11,24 +11,29 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUserServer = `-- name: CreateUserServer :one
-INSERT INTO user_servers (user_id, server_id, role)
-VALUES ($1, $2, $3)
-RETURNING user_id, server_id, role
+INSERT INTO user_servers (user_id, server_id)
+VALUES ($1, $2)
+RETURNING user_id, server_id, invite_code, muted_until
 `
 
 type CreateUserServerParams struct {
 	UserID   uuid.UUID `json:"user_id"`
 	ServerID uuid.UUID `json:"server_id"`
-	Role     string    `json:"role"`
 }
 
 func (q *Queries) CreateUserServer(ctx context.Context, arg CreateUserServerParams) (UserServer, error) {
-	row := q.db.QueryRowContext(ctx, createUserServer, arg.UserID, arg.ServerID, arg.Role)
+	row := q.db.QueryRowContext(ctx, createUserServer, arg.UserID, arg.ServerID)
 	var i UserServer
-	err := row.Scan(&i.UserID, &i.ServerID, &i.Role)
+	err := row.Scan(
+		&i.UserID,
+		&i.ServerID,
+		&i.InviteCode,
+		&i.MutedUntil,
+	)
 	return i, err
 }
 
@@ -47,8 +52,104 @@ func (q *Queries) DeleteUserServer(ctx context.Context, arg DeleteUserServerPara
 	return err
 }
 
+const getServerMembers = `-- name: GetServerMembers :many
+SELECT u.id,
+    u.handle,
+    u.avatar_url,
+    u.is_bot,
+    us.muted_until,
+    COALESCE(
+        array_agg(mr.role_id) FILTER (
+            WHERE mr.role_id IS NOT NULL
+        ),
+        '{}'
+    )::UUID [] AS role_ids
+FROM user_servers us
+    JOIN users u ON u.id = us.user_id
+    LEFT JOIN member_roles mr ON mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+WHERE us.server_id = $1
+    AND (u.handle, u.id) > (
+        $2::TEXT,
+        $3::UUID
+    )
+    AND lower(u.handle) LIKE lower($4::TEXT) || '%'
+    AND (
+        $5::UUID IS NULL
+        OR EXISTS (
+            SELECT 1
+            FROM member_roles f
+            WHERE f.user_id = us.user_id
+                AND f.server_id = us.server_id
+                AND f.role_id = $5
+        )
+    )
+GROUP BY u.id,
+    us.muted_until
+ORDER BY u.handle,
+    u.id
+LIMIT $6
+`
+
+type GetServerMembersParams struct {
+	ServerID     uuid.UUID     `json:"server_id"`
+	AfterHandle  string        `json:"after_handle"`
+	AfterID      uuid.UUID     `json:"after_id"`
+	HandlePrefix string        `json:"handle_prefix"`
+	RoleID       uuid.NullUUID `json:"role_id"`
+	PageSize     int32         `json:"page_size"`
+}
+
+type GetServerMembersRow struct {
+	ID         uuid.UUID      `json:"id"`
+	Handle     string         `json:"handle"`
+	AvatarUrl  sql.NullString `json:"avatar_url"`
+	IsBot      bool           `json:"is_bot"`
+	MutedUntil sql.NullTime   `json:"muted_until"`
+	RoleIds    []uuid.UUID    `json:"role_ids"`
+}
+
+// Lists a server's members ordered by handle then id, starting after the
+// cursor. An empty prefix matches every handle and a null role every member.
+func (q *Queries) GetServerMembers(ctx context.Context, arg GetServerMembersParams) ([]GetServerMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getServerMembers,
+		arg.ServerID,
+		arg.AfterHandle,
+		arg.AfterID,
+		arg.HandlePrefix,
+		arg.RoleID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetServerMembersRow
+	for rows.Next() {
+		var i GetServerMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.AvatarUrl,
+			&i.IsBot,
+			&i.MutedUntil,
+			pq.Array(&i.RoleIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserServer = `-- name: GetUserServer :one
-SELECT user_id, server_id, role FROM user_servers
+SELECT user_id, server_id, invite_code, muted_until FROM user_servers
 WHERE user_id = $1 AND server_id = $2
 `
 
@@ -60,7 +161,12 @@ type GetUserServerParams struct {
 func (q *Queries) GetUserServer(ctx context.Context, arg GetUserServerParams) (UserServer, error) {
 	row := q.db.QueryRowContext(ctx, getUserServer, arg.UserID, arg.ServerID)
 	var i UserServer
-	err := row.Scan(&i.UserID, &i.ServerID, &i.Role)
+	err := row.Scan(
+		&i.UserID,
+		&i.ServerID,
+		&i.InviteCode,
+		&i.MutedUntil,
+	)
 	return i, err
 }
 
@@ -76,6 +182,7 @@ SELECT s.id AS server_id,
     s.server_level,
     s.max_members,
     s.invite_code,
+    s.boost_count,
     s.created_at AS server_created_at,
     s.updated_at AS server_updated_at
 FROM user_servers us
@@ -96,6 +203,7 @@ type GetUserServersRow struct {
 	ServerLevel     sql.NullInt32  `json:"server_level"`
 	MaxMembers      sql.NullInt32  `json:"max_members"`
 	InviteCode      string         `json:"invite_code"`
+	BoostCount      int32          `json:"boost_count"`
 	ServerCreatedAt time.Time      `json:"server_created_at"`
 	ServerUpdatedAt time.Time      `json:"server_updated_at"`
 }
@@ -121,6 +229,7 @@ func (q *Queries) GetUserServers(ctx context.Context, userID uuid.UUID) ([]GetUs
 			&i.ServerLevel,
 			&i.MaxMembers,
 			&i.InviteCode,
+			&i.BoostCount,
 			&i.ServerCreatedAt,
 			&i.ServerUpdatedAt,
 		); err != nil {
@@ -136,3 +245,41 @@ func (q *Queries) GetUserServers(ctx context.Context, userID uuid.UUID) ([]GetUs
 	}
 	return items, nil
 }
+
+const joinServerMember = `-- name: JoinServerMember :one
+WITH capacity AS (
+    SELECT id
+    FROM servers
+    WHERE id = $1
+        AND (
+            max_members IS NULL
+            OR COALESCE(member_count, 0) < max_members
+        )
+    FOR UPDATE
+)
+INSERT INTO user_servers (user_id, server_id)
+SELECT $2,
+    capacity.id
+FROM capacity
+RETURNING user_id, server_id, invite_code, muted_until
+`
+
+type JoinServerMemberParams struct {
+	ServerID uuid.UUID `json:"server_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+// Adds the member while the server is below max_members. Locking the server
+// row makes concurrent joins wait their turn and re-check the cap against
+// the count the user_servers trigger keeps, so the cap cannot be overrun.
+func (q *Queries) JoinServerMember(ctx context.Context, arg JoinServerMemberParams) (UserServer, error) {
+	row := q.db.QueryRowContext(ctx, joinServerMember, arg.ServerID, arg.UserID)
+	var i UserServer
+	err := row.Scan(
+		&i.UserID,
+		&i.ServerID,
+		&i.InviteCode,
+		&i.MutedUntil,
+	)
+	return i, err
+}
@@ -30,7 +30,7 @@ VALUES (
         $5,
         $6
     )
-RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at
+RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
 `
 
 type CreateUserStandardParams struct {
@@ -65,6 +65,8 @@ func (q *Queries) CreateUserStandard(ctx context.Context, arg CreateUserStandard
 		&i.IsVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
 	)
 	return i, err
 }
@@ -80,7 +82,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at
+SELECT id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
 FROM users
 WHERE email = $1
 `
@@ -101,12 +103,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.IsVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at
+SELECT id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
 FROM users
 WHERE id = $1
 `
@@ -127,6 +131,45 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.IsVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+	)
+	return i, err
+}
+
+const setUserVerified = `-- name: SetUserVerified :one
+UPDATE users
+SET is_verified = TRUE,
+    updated_at = $2
+WHERE id = $1
+    AND email = $3
+RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
+`
+
+type SetUserVerifiedParams struct {
+	ID        uuid.UUID `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Email     string    `json:"email"`
+}
+
+func (q *Queries) SetUserVerified(ctx context.Context, arg SetUserVerifiedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserVerified, arg.ID, arg.UpdatedAt, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.IsActive,
+		&i.Handle,
+		&i.FirstName,
+		&i.LastName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsVerified,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
 	)
 	return i, err
 }
@@ -135,7 +178,7 @@ const updateUserAvatarByID = `-- name: UpdateUserAvatarByID :one
 UPDATE users
 SET avatar_url = $1
 WHERE id = $2
-RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at
+RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
 `
 
 type UpdateUserAvatarByIDParams struct {
@@ -159,20 +202,26 @@ func (q *Queries) UpdateUserAvatarByID(ctx context.Context, arg UpdateUserAvatar
 		&i.IsVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
 	)
 	return i, err
 }
 
 const updateUserByID = `-- name: UpdateUserByID :one
 UPDATE users
-SET email = $1,
+SET is_verified = CASE
+        WHEN email = $1 THEN is_verified
+        ELSE FALSE
+    END,
+    email = $1,
     handle = $2,
     first_name = $3,
     last_name = $4,
     bio = $5,
     updated_at = $6
 WHERE id = $7
-RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at
+RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
 `
 
 type UpdateUserByIDParams struct {
@@ -209,6 +258,44 @@ func (q *Queries) UpdateUserByID(ctx context.Context, arg UpdateUserByIDParams)
 		&i.IsVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET password = $2,
+    updated_at = $3
+WHERE id = $1
+RETURNING id, email, password, is_active, handle, first_name, last_name, bio, avatar_url, is_verified, created_at, updated_at, is_bot, bot_owner_id
+`
+
+type UpdateUserPasswordParams struct {
+	ID        uuid.UUID      `json:"id"`
+	Password  sql.NullString `json:"password"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.ID, arg.Password, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Password,
+		&i.IsActive,
+		&i.Handle,
+		&i.FirstName,
+		&i.LastName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsVerified,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsBot,
+		&i.BotOwnerID,
 	)
 	return i, err
 }
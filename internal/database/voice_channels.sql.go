@@ -140,6 +140,57 @@ func (q *Queries) GetServerVoiceChannels(ctx context.Context, serverID uuid.UUID
 	return items, nil
 }
 
+const getUserVoiceChannelMemberships = `-- name: GetUserVoiceChannelMemberships :many
+SELECT user_id, channel_id, server_id
+FROM voice_channel_members
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserVoiceChannelMemberships(ctx context.Context, userID uuid.UUID) ([]VoiceChannelMember, error) {
+	rows, err := q.db.QueryContext(ctx, getUserVoiceChannelMemberships, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []VoiceChannelMember
+	for rows.Next() {
+		var i VoiceChannelMember
+		if err := rows.Scan(&i.UserID, &i.ChannelID, &i.ServerID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVoiceChannelByID = `-- name: GetVoiceChannelByID :one
+SELECT id, owner_id, server_id, language_id, channel_name, last_active, is_locked, created_at, updated_at FROM voice_channels
+WHERE id = $1
+`
+
+func (q *Queries) GetVoiceChannelByID(ctx context.Context, id uuid.UUID) (VoiceChannel, error) {
+	row := q.db.QueryRowContext(ctx, getVoiceChannelByID, id)
+	var i VoiceChannel
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.ServerID,
+		&i.LanguageID,
+		&i.ChannelName,
+		&i.LastActive,
+		&i.IsLocked,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const joinVoiceChannel = `-- name: JoinVoiceChannel :one
 INSERT INTO voice_channel_members (
     user_id,
@@ -163,6 +214,22 @@ func (q *Queries) JoinVoiceChannel(ctx context.Context, arg JoinVoiceChannelPara
 	return i, err
 }
 
+const leaveServerVoiceChannels = `-- name: LeaveServerVoiceChannels :exec
+DELETE FROM voice_channel_members
+WHERE user_id = $1
+    AND server_id = $2
+`
+
+type LeaveServerVoiceChannelsParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	ServerID uuid.UUID `json:"server_id"`
+}
+
+func (q *Queries) LeaveServerVoiceChannels(ctx context.Context, arg LeaveServerVoiceChannelsParams) error {
+	_, err := q.db.ExecContext(ctx, leaveServerVoiceChannels, arg.UserID, arg.ServerID)
+	return err
+}
+
 const leaveVoiceChannelByUser = `-- name: LeaveVoiceChannelByUser :exec
 DELETE FROM voice_channel_members
 WHERE user_id = $1
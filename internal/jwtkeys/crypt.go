@@ -0,0 +1,60 @@
+package jwtkeys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// sealer encrypts private keys at rest with AES-256-GCM. A nil sealer stores
+// keys as plain PKCS#8.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(secret string) (*sealer, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealer{aead: aead}, nil
+}
+
+func (s *sealer) seal(plaintext []byte) ([]byte, error) {
+	if s == nil {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return s.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (s *sealer) open(ciphertext []byte) ([]byte, error) {
+	if s == nil {
+		return ciphertext, nil
+	}
+
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, errors.New("sealed key is too short")
+	}
+
+	nonce, sealed := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	return s.aead.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,123 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmEdDSA = "EdDSA"
+	AlgorithmRS256 = "RS256"
+)
+
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+}
+
+func (k *Key) signingMethod() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmRS256 {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+func generateKey(id, algorithm string) (*Key, error) {
+	var private crypto.Signer
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = priv
+	case AlgorithmRS256:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private = priv
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	return &Key{ID: id, Algorithm: algorithm, private: private}, nil
+}
+
+func parseKey(id, algorithm string, der []byte) (*Key, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	switch private.(type) {
+	case ed25519.PrivateKey:
+		if algorithm != AlgorithmEdDSA {
+			return nil, fmt.Errorf("ed25519 key stored as %s", algorithm)
+		}
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("rsa key stored as %s", algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+
+	return &Key{ID: id, Algorithm: algorithm, private: private}, nil
+}
+
+func (k *Key) marshal() ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(k.private)
+}
+
+// JSONWebKey is the public half of a signing key as published in the JWKS.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func (k *Key) jwk() JSONWebKey {
+	jwk := JSONWebKey{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Algorithm,
+	}
+
+	switch pub := k.private.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	}
+
+	return jwk
+}
@@ -0,0 +1,374 @@
+package jwtkeys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+// Store persists signing keys so every API instance signs and verifies with
+// the same set and keys survive restarts.
+type Store interface {
+	CreateSigningKey(ctx context.Context, arg database.CreateSigningKeyParams) (database.SigningKey, error)
+	GetUnexpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) ([]database.SigningKey, error)
+	RetireSigningKeys(ctx context.Context, arg database.RetireSigningKeysParams) error
+	DeleteExpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) error
+	LockSigningKeys(ctx context.Context) error
+	RunInTx(ctx context.Context, fn func(Store) error) error
+}
+
+// unknownKeyReloadEvery limits how often a token with an unknown kid may
+// trigger a reload, so made-up key IDs cannot hammer the store.
+const unknownKeyReloadEvery = 30 * time.Second
+
+type Config struct {
+	// Algorithm used for newly generated keys, EdDSA or RS256.
+	Algorithm string
+	// RotateEvery is how long a key signs new tokens before it is replaced.
+	RotateEvery time.Duration
+	// VerifyFor is how long a replaced key keeps verifying tokens. It must
+	// outlive the longest token lifetime.
+	VerifyFor time.Duration
+	// LegacySecret, when set, verifies HS256 tokens signed before the move to
+	// asymmetric keys. It is never used to sign.
+	LegacySecret string
+	// EncryptionSecret encrypts private keys at rest.
+	EncryptionSecret string
+}
+
+// KeySet signs tokens with the newest key and verifies tokens signed by any
+// unexpired key, identified by the kid header.
+type KeySet struct {
+	store  Store
+	config Config
+	sealer *sealer
+
+	mu      sync.RWMutex
+	current *Key
+	created time.Time
+	keys    map[string]*Key
+
+	// reloadMu guards lastUnknownReload and lets one unknown kid reload at
+	// a time.
+	reloadMu          sync.Mutex
+	lastUnknownReload time.Time
+}
+
+// keyState is the set of keys loaded from the store.
+type keyState struct {
+	keys    map[string]*Key
+	current *Key
+	created time.Time
+}
+
+func New(ctx context.Context, store Store, cfg Config) (*KeySet, error) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = AlgorithmEdDSA
+	}
+	if cfg.Algorithm != AlgorithmEdDSA && cfg.Algorithm != AlgorithmRS256 {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", cfg.Algorithm)
+	}
+	if cfg.RotateEvery <= 0 {
+		return nil, errors.New("rotation interval must be positive")
+	}
+
+	s, err := newSealer(cfg.EncryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		log.Print("JWT signing keys are stored unencrypted; set an encryption secret")
+	}
+
+	ks := &KeySet{
+		store:  store,
+		config: cfg,
+		sealer: s,
+	}
+
+	if err := ks.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if ks.needsRotation() {
+		if err := ks.Rotate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+// Sign signs claims with the current key and sets its kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.current
+	ks.mu.RUnlock()
+
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key for a parsed token. Tokens without a
+// kid are legacy HS256 tokens and are only accepted while a legacy secret is
+// configured. An unknown kid reloads the keys first, since another instance
+// may have just rotated.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && ks.config.LegacySecret != "" {
+			return []byte(ks.config.LegacySecret), nil
+		}
+		return nil, errors.New("token has no key ID")
+	}
+
+	key, ok := ks.lookup(kid)
+	if !ok {
+		key, ok = ks.reloadForUnknownKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.private.Public(), nil
+}
+
+// ValidMethods lists the algorithms Keyfunc may be asked to verify.
+func (ks *KeySet) ValidMethods() []string {
+	methods := []string{AlgorithmEdDSA, AlgorithmRS256}
+	if ks.config.LegacySecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// JWKS returns the public keys of every key that can still verify tokens.
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	return set
+}
+
+func (ks *KeySet) lookup(kid string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// reloadForUnknownKey reloads the keys when a token names a kid this
+// instance has not loaded, at most once every unknownKeyReloadEvery.
+func (ks *KeySet) reloadForUnknownKey(kid string) (*Key, bool) {
+	ks.reloadMu.Lock()
+	defer ks.reloadMu.Unlock()
+
+	// Another token may have triggered the reload while this one waited.
+	if key, ok := ks.lookup(kid); ok {
+		return key, true
+	}
+
+	if time.Since(ks.lastUnknownReload) < unknownKeyReloadEvery {
+		return nil, false
+	}
+	ks.lastUnknownReload = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ks.Reload(ctx); err != nil {
+		log.Printf("Failed to reload signing keys for key ID %q: %v", kid, err)
+		return nil, false
+	}
+
+	return ks.lookup(kid)
+}
+
+// Reload replaces the in-memory keys with the unexpired keys in the store,
+// picking up keys rotated by other instances.
+func (ks *KeySet) Reload(ctx context.Context) error {
+	state, err := ks.load(ctx, ks.store)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = state.keys
+	ks.current = state.current
+	ks.created = state.created
+	ks.mu.Unlock()
+
+	return nil
+}
+
+func (ks *KeySet) load(ctx context.Context, store Store) (keyState, error) {
+	stored, err := store.GetUnexpiredSigningKeys(ctx, sql.NullTime{
+		Time:  time.Now().UTC(),
+		Valid: true,
+	})
+	if err != nil {
+		return keyState{}, fmt.Errorf("loading signing keys: %w", err)
+	}
+
+	state := keyState{keys: make(map[string]*Key, len(stored))}
+
+	for _, s := range stored {
+		der, err := ks.sealer.open(s.PrivateKey)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", s.ID, err)
+			continue
+		}
+
+		key, err := parseKey(s.ID, s.Algorithm, der)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v", s.ID, err)
+			continue
+		}
+
+		state.keys[key.ID] = key
+
+		// Keys are ordered by creation, so the last unretired one wins.
+		if !s.RetiredAt.Valid {
+			state.current = key
+			state.created = s.CreatedAt
+		}
+	}
+
+	return state, nil
+}
+
+// Rotate creates a new signing key and schedules every older key to expire
+// once the tokens it signed can no longer be valid. Instances rotate one at
+// a time under a database lock, and an instance that finds the key already
+// replaced by another leaves it alone, so concurrent rotations cannot
+// retire each other's new keys.
+func (ks *KeySet) Rotate(ctx context.Context) error {
+	err := ks.store.RunInTx(ctx, func(store Store) error {
+		if err := store.LockSigningKeys(ctx); err != nil {
+			return fmt.Errorf("locking signing keys: %w", err)
+		}
+
+		state, err := ks.load(ctx, store)
+		if err != nil {
+			return err
+		}
+		if !ks.due(state) {
+			return nil
+		}
+
+		key, err := generateKey(uuid.NewString(), ks.config.Algorithm)
+		if err != nil {
+			return err
+		}
+
+		der, err := key.marshal()
+		if err != nil {
+			return err
+		}
+
+		sealed, err := ks.sealer.seal(der)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+
+		_, err = store.CreateSigningKey(ctx, database.CreateSigningKeyParams{
+			ID:         key.ID,
+			Algorithm:  key.Algorithm,
+			PrivateKey: sealed,
+			CreatedAt:  now,
+		})
+		if err != nil {
+			return fmt.Errorf("saving signing key: %w", err)
+		}
+
+		err = store.RetireSigningKeys(ctx, database.RetireSigningKeysParams{
+			RetiredAt: sql.NullTime{Time: now, Valid: true},
+			ExpiresAt: sql.NullTime{Time: now.Add(ks.config.VerifyFor), Valid: true},
+			ID:        key.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("retiring signing keys: %w", err)
+		}
+
+		log.Printf("Rotated JWT signing key, new key ID %s", key.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return ks.Reload(ctx)
+}
+
+// Run rotates the signing key when it is due and periodically reloads keys
+// from the store until ctx is cancelled.
+func (ks *KeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(ctx); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+				continue
+			}
+
+			if ks.needsRotation() {
+				if err := ks.Rotate(ctx); err != nil {
+					log.Printf("Failed to rotate signing key: %v", err)
+				}
+			}
+
+			err := ks.store.DeleteExpiredSigningKeys(ctx, sql.NullTime{
+				Time:  time.Now().UTC(),
+				Valid: true,
+			})
+			if err != nil {
+				log.Printf("Failed to delete expired signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (ks *KeySet) needsRotation() bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.due(keyState{current: ks.current, created: ks.created})
+}
+
+// due reports whether state has no current key or one that should be
+// replaced.
+func (ks *KeySet) due(state keyState) bool {
+	return state.current == nil ||
+		state.current.Algorithm != ks.config.Algorithm ||
+		time.Since(state.created) >= ks.config.RotateEvery
+}
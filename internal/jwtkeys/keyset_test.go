@@ -0,0 +1,281 @@
+package jwtkeys
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+// fakeStore keeps signing keys in memory. RunInTx holds a lock for the whole
+// transaction, standing in for the advisory lock rotation takes.
+type fakeStore struct {
+	mu   sync.Mutex
+	tx   sync.Mutex
+	rows []database.SigningKey
+
+	loads   int
+	creates int
+	locks   int
+}
+
+func (f *fakeStore) CreateSigningKey(ctx context.Context, arg database.CreateSigningKeyParams) (database.SigningKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row := database.SigningKey{
+		ID:         arg.ID,
+		Algorithm:  arg.Algorithm,
+		PrivateKey: arg.PrivateKey,
+		CreatedAt:  arg.CreatedAt,
+	}
+	f.rows = append(f.rows, row)
+	f.creates++
+	return row, nil
+}
+
+func (f *fakeStore) GetUnexpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) ([]database.SigningKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.loads++
+	var rows []database.SigningKey
+	for _, row := range f.rows {
+		if !row.ExpiresAt.Valid || row.ExpiresAt.Time.After(expiresAt.Time) {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].CreatedAt.Before(rows[j].CreatedAt) })
+	return rows, nil
+}
+
+func (f *fakeStore) RetireSigningKeys(ctx context.Context, arg database.RetireSigningKeysParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, row := range f.rows {
+		if !row.RetiredAt.Valid && row.ID != arg.ID {
+			f.rows[i].RetiredAt = arg.RetiredAt
+			f.rows[i].ExpiresAt = arg.ExpiresAt
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) DeleteExpiredSigningKeys(ctx context.Context, expiresAt sql.NullTime) error {
+	return nil
+}
+
+func (f *fakeStore) LockSigningKeys(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.locks++
+	return nil
+}
+
+func (f *fakeStore) RunInTx(ctx context.Context, fn func(Store) error) error {
+	f.tx.Lock()
+	defer f.tx.Unlock()
+
+	return fn(f)
+}
+
+// age moves every key's creation back by d, as if that much time had passed.
+func (f *fakeStore) age(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.rows {
+		f.rows[i].CreatedAt = f.rows[i].CreatedAt.Add(-d)
+	}
+}
+
+func (f *fakeStore) counts() (loads, creates int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.loads, f.creates
+}
+
+func newTestKeySet(t *testing.T, store *fakeStore) *KeySet {
+	t.Helper()
+
+	ks, err := New(context.Background(), store, Config{
+		RotateEvery: time.Hour,
+		VerifyFor:   2 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+func sign(t *testing.T, ks *KeySet) string {
+	t.Helper()
+
+	token, err := ks.Sign(jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func verify(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	return err
+}
+
+func TestNewCreatesAndSignsWithKey(t *testing.T) {
+	store := &fakeStore{}
+	ks := newTestKeySet(t, store)
+
+	if _, creates := store.counts(); creates != 1 {
+		t.Fatalf("created %d keys, want 1", creates)
+	}
+
+	if err := verify(ks, sign(t, ks)); err != nil {
+		t.Fatalf("token signed by the key set did not verify: %v", err)
+	}
+
+	// A second instance starting against the same store reuses the key.
+	newTestKeySet(t, store)
+	if _, creates := store.counts(); creates != 1 {
+		t.Fatalf("created %d keys, want 1", creates)
+	}
+}
+
+func TestRotateKeepsRetiredKeyVerifying(t *testing.T) {
+	store := &fakeStore{}
+	ks := newTestKeySet(t, store)
+	old := sign(t, ks)
+
+	store.age(2 * time.Hour)
+	if err := ks.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !ks.needsRotation() {
+		t.Fatal("stale key does not need rotation")
+	}
+	if err := ks.Rotate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ks.needsRotation() {
+		t.Fatal("key still needs rotation after rotating")
+	}
+	if err := verify(ks, old); err != nil {
+		t.Fatalf("token signed by the retired key did not verify: %v", err)
+	}
+	if err := verify(ks, sign(t, ks)); err != nil {
+		t.Fatalf("token signed by the new key did not verify: %v", err)
+	}
+}
+
+func TestConcurrentRotationsKeepOneKey(t *testing.T) {
+	store := &fakeStore{}
+	a := newTestKeySet(t, store)
+	b := newTestKeySet(t, store)
+
+	store.age(2 * time.Hour)
+	locks := store.locks
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, ks := range []*KeySet{a, b} {
+		wg.Add(1)
+		go func(i int, ks *KeySet) {
+			defer wg.Done()
+			errs[i] = ks.Rotate(context.Background())
+		}(i, ks)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, creates := store.counts(); creates != 2 {
+		t.Fatalf("created %d keys, want the initial key and one rotation", creates)
+	}
+	if store.locks-locks != 2 {
+		t.Fatalf("took the rotation lock %d times, want 2", store.locks-locks)
+	}
+
+	for name, ks := range map[string]*KeySet{"a": a, "b": b} {
+		if _, err := ks.Sign(jwt.RegisteredClaims{}); err != nil {
+			t.Fatalf("instance %s cannot sign after rotating: %v", name, err)
+		}
+	}
+	if a.current.ID != b.current.ID {
+		t.Fatalf("instances sign with different keys %s and %s", a.current.ID, b.current.ID)
+	}
+}
+
+func TestKeyfuncReloadsUnknownKey(t *testing.T) {
+	store := &fakeStore{}
+	a := newTestKeySet(t, store)
+	b := newTestKeySet(t, store)
+
+	store.age(2 * time.Hour)
+	if err := a.Rotate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verify(b, sign(t, a)); err != nil {
+		t.Fatalf("token signed by a key rotated on another instance did not verify: %v", err)
+	}
+}
+
+func TestKeyfuncLimitsUnknownKeyReloads(t *testing.T) {
+	store := &fakeStore{}
+	ks := newTestKeySet(t, store)
+
+	bogus := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{})
+	bogus.Header["kid"] = "made-up"
+
+	before, _ := store.counts()
+	for i := 0; i < 3; i++ {
+		if _, err := ks.Keyfunc(bogus); err == nil {
+			t.Fatal("unknown key ID was accepted")
+		}
+	}
+	if loads, _ := store.counts(); loads != before+1 {
+		t.Fatalf("unknown key IDs reloaded keys %d times, want 1", loads-before)
+	}
+
+	ks.lastUnknownReload = time.Now().Add(-unknownKeyReloadEvery)
+	if _, err := ks.Keyfunc(bogus); err == nil {
+		t.Fatal("unknown key ID was accepted")
+	}
+	if loads, _ := store.counts(); loads != before+2 {
+		t.Fatalf("unknown key ID did not reload after the limit passed")
+	}
+}
+
+func TestKeyfuncLegacyTokens(t *testing.T) {
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := newTestKeySet(t, &fakeStore{})
+	if err := verify(ks, legacy); err == nil {
+		t.Fatal("legacy token accepted without a legacy secret")
+	}
+
+	ks.config.LegacySecret = "secret"
+	if err := verify(ks, legacy); err != nil {
+		t.Fatalf("legacy token did not verify: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package jwtkeys
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+// DBStore is the Store used in production. Its queries run on the
+// connection pool unless it was handed out by RunInTx.
+type DBStore struct {
+	*database.Queries
+	db *sql.DB
+	tx *sql.Tx
+}
+
+func NewDBStore(db *sql.DB) *DBStore {
+	return &DBStore{
+		Queries: database.New(db),
+		db:      db,
+	}
+}
+
+// RunInTx runs fn with a store bound to one transaction, committing if fn
+// returns nil and rolling back otherwise. Calling RunInTx on a store that is
+// already in a transaction runs fn in that transaction.
+func (s *DBStore) RunInTx(ctx context.Context, fn func(Store) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(&DBStore{
+		Queries: s.Queries.WithTx(tx),
+		db:      s.db,
+		tx:      tx,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,53 @@
+// Package levels defines the perks a server unlocks as its members boost it.
+package levels
+
+// Level is one boost tier and the limits that apply while a server is at it.
+type Level struct {
+	Level          int32
+	RequiredBoosts int32
+	MaxMembers     int32
+	// UploadLimit is the largest file, in bytes, members may upload.
+	UploadLimit int64
+	EmojiSlots  int32
+}
+
+const megabyte = 1 << 20
+
+// Levels lists every tier in ascending order. Level 0 applies to servers
+// nobody has boosted.
+var Levels = []Level{
+	{Level: 0, RequiredBoosts: 0, MaxMembers: 50, UploadLimit: 8 * megabyte, EmojiSlots: 10},
+	{Level: 1, RequiredBoosts: 2, MaxMembers: 100, UploadLimit: 25 * megabyte, EmojiSlots: 25},
+	{Level: 2, RequiredBoosts: 7, MaxMembers: 250, UploadLimit: 50 * megabyte, EmojiSlots: 50},
+	{Level: 3, RequiredBoosts: 14, MaxMembers: 500, UploadLimit: 100 * megabyte, EmojiSlots: 100},
+}
+
+// Get returns the tier for level, clamped to the defined range.
+func Get(level int32) Level {
+	if level < 0 {
+		return Levels[0]
+	}
+	if int(level) >= len(Levels) {
+		return Levels[len(Levels)-1]
+	}
+	return Levels[level]
+}
+
+// Thresholds returns the boosts needed for each level above 0. The boost
+// queries count how many a server has passed to find its level.
+func Thresholds() []int32 {
+	thresholds := make([]int32, 0, len(Levels)-1)
+	for _, level := range Levels[1:] {
+		thresholds = append(thresholds, level.RequiredBoosts)
+	}
+	return thresholds
+}
+
+// MaxMembers returns each level's member cap, indexed by level.
+func MaxMembers() []int32 {
+	caps := make([]int32, len(Levels))
+	for i, level := range Levels {
+		caps[i] = level.MaxMembers
+	}
+	return caps
+}
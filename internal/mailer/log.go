@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer writes messages to the log and, when dir is set, to one file per
+// message so they can be inspected during local development and tests.
+type LogMailer struct {
+	dir string
+}
+
+func NewLogMailer(dir string) *LogMailer {
+	return &LogMailer{dir: dir}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+
+	if m.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	// The recipient is hashed rather than used as is, so no address can
+	// name a path outside dir.
+	recipient := sha256.Sum256([]byte(msg.To))
+	name := fmt.Sprintf("%d-%x.eml", time.Now().UnixNano(), recipient[:8])
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", msg.To, msg.Subject, msg.Body)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
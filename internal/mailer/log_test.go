@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogMailerKeepsFilesInDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "mail")
+
+	err := NewLogMailer(dir).Send(context.Background(), Message{
+		To:      "../../escaped/x@example.com",
+		Subject: "Verify",
+		Body:    "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].IsDir() {
+		t.Fatalf("mail dir holds %v, want one message file", entries)
+	}
+
+	outside, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outside) != 1 {
+		t.Fatalf("mail was written outside the mail dir: %v", outside)
+	}
+}
+
+func TestNewFromEnvRequiresBackend(t *testing.T) {
+	t.Setenv("MAIL_BACKEND", "")
+	if _, err := NewFromEnv(); err == nil {
+		t.Fatal("mailer built without MAIL_BACKEND")
+	}
+
+	t.Setenv("MAIL_BACKEND", "log")
+	if _, err := NewFromEnv(); err != nil {
+		t.Fatalf("log backend: %v", err)
+	}
+}
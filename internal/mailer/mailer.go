@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional mail such as verification links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewFromEnv builds the mailer selected by MAIL_BACKEND. There is no
+// default: the log backend prints reset and verification tokens, so it must
+// be chosen explicitly, for local development.
+func NewFromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@gleamspeak.local"
+	}
+
+	switch backend := os.Getenv("MAIL_BACKEND"); backend {
+	case "":
+		return nil, errors.New("MAIL_BACKEND is not set; use smtp, or log for local development")
+	case "log":
+		return NewLogMailer(os.Getenv("MAIL_LOG_DIR")), nil
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail backend: %s", backend)
+	}
+}
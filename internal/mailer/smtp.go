@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP_HOST is required for the smtp mail backend")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &SMTPMailer{cfg: cfg, auth: auth}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("invalid mail header")
+	}
+
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.cfg.From, msg.To, msg.Subject, msg.Body)
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	if err := smtp.SendMail(addr, m.auth, m.cfg.From, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// ProvidersFromEnv builds the providers listed in OIDC_PROVIDERS, a comma
+// separated list of names. Each name is configured through
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL and optionally OIDC_<NAME>_SCOPES.
+func ProvidersFromEnv() (map[string]*Provider, error) {
+	providers := make(map[string]*Provider)
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			cfg.Scopes = strings.Fields(scopes)
+		}
+
+		provider, err := NewProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("oidc provider %s: %w", name, err)
+		}
+		providers[name] = provider
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,223 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a minimal OpenID Connect issuer that signs ID tokens with a
+// throwaway RSA key and enforces PKCE on the token endpoint.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	challenge string
+	nonce     string
+	email     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	m := &mockIssuer{key: key, email: "user@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != "valid-code" || CodeChallenge(r.PostForm.Get("code_verifier")) != m.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.sign(t, r.PostForm.Get("client_id"), m.nonce),
+			"expires_in":   3600,
+		})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) sign(t *testing.T, audience, nonce string) string {
+	t.Helper()
+
+	claims := IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.server.URL,
+			Subject:   "subject-123",
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+		Nonce:         nonce,
+		Email:         m.email,
+		EmailVerified: true,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatalf("signing id token: %v", err)
+	}
+	return signed
+}
+
+func newTestProvider(t *testing.T, issuer string) *Provider {
+	t.Helper()
+
+	provider, err := NewProvider(Config{
+		Name:        "mock",
+		Issuer:      issuer,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/v1/oauth/mock/callback",
+	})
+	if err != nil {
+		t.Fatalf("creating provider: %v", err)
+	}
+	return provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer.server.URL)
+	ctx := context.Background()
+
+	verifier, _ := RandomString()
+	issuer.challenge = CodeChallenge(verifier)
+	issuer.nonce = "nonce-abc"
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-xyz", issuer.nonce, issuer.challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parsing auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") != issuer.challenge {
+		t.Errorf("auth URL missing PKCE parameters: %s", authURL)
+	}
+	if query.Get("state") != "state-xyz" || query.Get("nonce") != issuer.nonce {
+		t.Errorf("auth URL missing state or nonce: %s", authURL)
+	}
+
+	token, err := provider.Exchange(ctx, "valid-code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, issuer.nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "subject-123" || claims.Email != issuer.email || !claims.EmailVerified {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer.server.URL)
+
+	verifier, _ := RandomString()
+	issuer.challenge = CodeChallenge(verifier)
+
+	if _, err := provider.Exchange(context.Background(), "valid-code", "wrong-verifier"); err == nil {
+		t.Fatal("expected exchange with wrong verifier to fail")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer.server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: issuer.sign(t, "client", "n1"),
+			nonce: "n1",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   issuer.sign(t, "client", "n1"),
+			nonce:   "n2",
+			wantErr: ErrNonceMismatch,
+		},
+		{
+			name:    "wrong audience",
+			token:   issuer.sign(t, "other-client", "n1"),
+			nonce:   "n1",
+			wantErr: ErrInvalidIDToken,
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-jwt",
+			nonce:   "n1",
+			wantErr: ErrInvalidIDToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.VerifyIDToken(ctx, tt.token, tt.nonce)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package permissions defines the per-server permission bits granted through
+// server roles and resolves a member's effective permissions.
+package permissions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+)
+
+type Permission int64
+
+const (
+	// Administrator grants every permission.
+	Administrator Permission = 1 << iota
+	ManageServer
+	ManageRoles
+	ManageChannels
+	ManageMessages
+	KickMembers
+	BanMembers
+	TimeoutMembers
+	MentionEveryone
+	CreateInvite
+	ViewChannels
+	SendMessages
+	ConnectVoice
+	// MoveMembers allows disconnecting other members from voice channels.
+	MoveMembers
+	// ViewAuditLog allows reading the server's audit log.
+	ViewAuditLog
+	// ManageWebhooks allows creating, editing and deleting channel webhooks.
+	ManageWebhooks
+)
+
+// All is every defined permission bit.
+const All = Administrator | ManageServer | ManageRoles | ManageChannels |
+	ManageMessages | KickMembers | BanMembers | TimeoutMembers |
+	MentionEveryone | CreateInvite | ViewChannels | SendMessages | ConnectVoice |
+	MoveMembers | ViewAuditLog | ManageWebhooks
+
+// Default is granted to every member through the server's default role.
+const Default = ViewChannels | SendMessages | ConnectVoice | CreateInvite
+
+// TimeoutRevoked is withheld from members while they are timed out.
+const TimeoutRevoked = SendMessages | ConnectVoice
+
+// Has reports whether perms include perm. Administrator implies everything.
+func (perms Permission) Has(perm Permission) bool {
+	return perms&Administrator != 0 || perms&perm == perm
+}
+
+// Valid reports whether perms only uses defined bits.
+func (perms Permission) Valid() bool {
+	return perms&^All == 0
+}
+
+// Member is a user's standing in one server.
+type Member struct {
+	UserID      uuid.UUID
+	ServerID    uuid.UUID
+	IsOwner     bool
+	Permissions Permission
+	// TopPosition is the position of the member's highest role. Members can
+	// only manage roles and members below it.
+	TopPosition int32
+	// MutedUntil is when the member's timeout ends. It is zero if they were
+	// never timed out.
+	MutedUntil time.Time
+}
+
+// Has reports whether the member holds perm. Server owners hold every
+// permission; timed out members lose TimeoutRevoked until the timeout ends.
+func (m Member) Has(perm Permission) bool {
+	if m.IsOwner {
+		return true
+	}
+	if perm&TimeoutRevoked != 0 && m.TimedOut(time.Now()) {
+		return false
+	}
+	return m.Permissions.Has(perm)
+}
+
+// TimedOut reports whether the member is timed out at now.
+func (m Member) TimedOut(now time.Time) bool {
+	return now.Before(m.MutedUntil)
+}
+
+// Outranks reports whether the member may manage something at position.
+func (m Member) Outranks(position int32) bool {
+	return m.IsOwner || m.TopPosition > position
+}
+
+// ErrNotMember is returned when the user does not belong to the server.
+var ErrNotMember = errors.New("user is not a member of the server")
+
+type Store interface {
+	GetMemberPermissions(ctx context.Context, arg database.GetMemberPermissionsParams) (database.GetMemberPermissionsRow, error)
+}
+
+// GetMember resolves the effective permissions of a user in a server.
+func GetMember(ctx context.Context, store Store, userID, serverID uuid.UUID) (Member, error) {
+	row, err := store.GetMemberPermissions(ctx, database.GetMemberPermissionsParams{
+		UserID:   userID,
+		ServerID: serverID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Member{}, ErrNotMember
+		}
+		return Member{}, err
+	}
+
+	return Member{
+		UserID:      userID,
+		ServerID:    serverID,
+		IsOwner:     row.OwnerID == userID,
+		Permissions: Permission(row.Permissions),
+		TopPosition: row.TopPosition,
+		MutedUntil:  row.MutedUntil.Time,
+	}, nil
+}
+
+type MemberLister interface {
+	GetServerMemberPermissions(ctx context.Context, serverID uuid.UUID) ([]database.GetServerMemberPermissionsRow, error)
+}
+
+// GetMembers resolves the effective permissions of every member of a server
+// in one query, for callers that would otherwise call GetMember per user.
+func GetMembers(ctx context.Context, lister MemberLister, serverID uuid.UUID) ([]Member, error) {
+	rows, err := lister.GetServerMemberPermissions(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, Member{
+			UserID:      row.UserID,
+			ServerID:    serverID,
+			IsOwner:     row.OwnerID == row.UserID,
+			Permissions: Permission(row.Permissions),
+			TopPosition: row.TopPosition,
+			MutedUntil:  row.MutedUntil.Time,
+		})
+	}
+	return members, nil
+}
+
+// HasPermission reports whether a user holds perm in a server. Users who are
+// not members hold no permissions.
+func HasPermission(ctx context.Context, store Store, userID, serverID uuid.UUID, perm Permission) (bool, error) {
+	member, err := GetMember(ctx, store, userID, serverID)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			return false, nil
+		}
+		return false, err
+	}
+	return member.Has(perm), nil
+}
@@ -59,3 +59,56 @@ func (r *RedisClient) SetJson(key string, value interface{}, expiration time.Dur
 
 	return r.rdb.Set(ctx, key, json, expiration).Err()
 }
+
+func (r *RedisClient) GetDelJSON(key string, dest interface{}) error {
+	ctx := context.Background()
+	val, err := r.rdb.GetDel(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal([]byte(val), dest)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisClient) Delete(keys ...string) error {
+	ctx := context.Background()
+	return r.rdb.Del(ctx, keys...).Err()
+}
+
+// Incr increments a counter and starts its expiration when it is created, so
+// the count covers a fixed window from the first increment.
+func (r *RedisClient) Incr(key string, expiration time.Duration) (int64, error) {
+	ctx := context.Background()
+	count, err := r.rdb.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		if err := r.rdb.Expire(ctx, key, expiration).Err(); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// TTL returns the remaining lifetime of a key, or zero when the key does not
+// exist or has no expiration.
+func (r *RedisClient) TTL(key string) (time.Duration, error) {
+	ctx := context.Background()
+	ttl, err := r.rdb.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
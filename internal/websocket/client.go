@@ -5,7 +5,10 @@ import (
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
 )
 
 var (
@@ -14,26 +17,56 @@ var (
 	pingInterval = (pongWait * 9 / 10)
 )
 
+// egressBuffer is how many events may queue for a client before further
+// events are dropped rather than stalling the sender.
+const egressBuffer = 64
+
 type ClientList map[*Client]bool
 
 type Client struct {
 	connection *websocket.Conn
 	manager    *Manager
 	egress     chan Event
+	user       database.User
+	sessionID  uuid.UUID
+	scopes     []string
+	botServers map[uuid.UUID]bool
 	userID     string
 	handle     string
 	chatroom   string
+	// chatServer is the server the chatroom belongs to, so the room can be
+	// cleared when the user leaves or is removed from that server.
+	chatServer uuid.UUID
 	voiceroom  string
 	server     string
 }
 
-func NewClient(conn *websocket.Conn, manager *Manager, id, handle string) *Client {
+func NewClient(conn *websocket.Conn, manager *Manager, user database.User, sessionID uuid.UUID, scopes []string) *Client {
 	return &Client{
 		connection: conn,
 		manager:    manager,
-		egress:     make(chan Event),
-		userID:     id,
-		handle:     handle,
+		egress:     make(chan Event, egressBuffer),
+		user:       user,
+		sessionID:  sessionID,
+		scopes:     scopes,
+		userID:     user.ID.String(),
+		handle:     user.Handle,
+	}
+}
+
+// hasScope reports whether the client may use scope. Clients connected with a
+// login session have no scope list and are not limited.
+func (c *Client) hasScope(scope string) bool {
+	return c.scopes == nil || common.HasScope(c.scopes, scope)
+}
+
+// deliver queues an event without blocking. Callers hold the manager lock
+// and have found the client in the manager, so its egress is still open.
+func (c *Client) deliver(event Event) {
+	select {
+	case c.egress <- event:
+	default:
+		log.Printf("dropping %s event for client %s: egress is full", event.Type, c.userID)
 	}
 }
 
@@ -71,6 +104,7 @@ func (c *Client) readMessages() {
 		log.Printf("payload: %v", string(request.Payload))
 		if err := c.manager.routeEvent(request, c); err != nil {
 			log.Println("error handling message", err)
+			c.sendError(request.Type, err)
 		}
 	}
 }
@@ -130,9 +164,22 @@ func (c *Client) writeMessages() {
 					Payload: response,
 				}
 
+			case EventError:
+				var response ErrorEvent
+				if err := json.Unmarshal(message.Payload, &response); err != nil {
+					log.Println("error unmarshaling error:", err)
+					continue
+				}
+				sentEvent = ReturnEventError{
+					Type:    message.Type,
+					Payload: response,
+				}
+
 			default:
-				log.Printf("unknown message type: %s", message.Type)
-				continue
+				// Events sent through SendToUser, SendToServer and
+				// SendToPermission were marshalled from typed payloads
+				// when they were built and go out unchanged.
+				sentEvent = message
 			}
 
 			data, err := json.Marshal(sentEvent)
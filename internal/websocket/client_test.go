@@ -0,0 +1,127 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	gorilla "github.com/gorilla/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
+)
+
+// connect serves the manager over a test server and dials it as user,
+// returning once the manager has registered the connection.
+func connect(t *testing.T, m *websocket.Manager, user database.User) *gorilla.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.ServeWs(w, r, user, uuid.New(), nil)
+	}))
+	t.Cleanup(server.Close)
+
+	header := http.Header{"Origin": {"http://localhost:5173"}}
+	conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	deadline := time.Now().Add(time.Second)
+	for !m.Online(user.ID)[user.ID] {
+		if time.Now().After(deadline) {
+			t.Fatal("connection was never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+// readEvent reads the next frame off conn.
+func readEvent(t *testing.T, conn *gorilla.Conn) websocket.Event {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	var event websocket.Event
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("reading event: %v", err)
+	}
+	return event
+}
+
+func TestSendToUserReachesClient(t *testing.T) {
+	eventTypes := []string{
+		websocket.EventJoinRequestCreated,
+		websocket.EventJoinRequestDecided,
+		websocket.EventMemberKicked,
+		websocket.EventMemberBanned,
+		websocket.EventMemberTimedOut,
+		websocket.EventServerUpdated,
+	}
+
+	for _, eventType := range eventTypes {
+		t.Run(eventType, func(t *testing.T) {
+			m := websocket.NewManager(nil, nil)
+			user := database.User{ID: uuid.New(), Handle: "target"}
+			conn := connect(t, m, user)
+
+			targetID := uuid.New()
+			payload := map[string]any{"id": targetID, "reason": "spam"}
+			if err := m.SendToUser(user.ID, eventType, payload); err != nil {
+				t.Fatal(err)
+			}
+
+			event := readEvent(t, conn)
+			if event.Type != eventType {
+				t.Fatalf("type = %q, want %q", event.Type, eventType)
+			}
+
+			var got struct {
+				ID     uuid.UUID `json:"id"`
+				Reason string    `json:"reason"`
+			}
+			if err := json.Unmarshal(event.Payload, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != targetID || got.Reason != "spam" {
+				t.Fatalf("payload = %s, want id %s and reason spam", event.Payload, targetID)
+			}
+		})
+	}
+}
+
+func TestSendToUserDoesNotBlockOnSlowClient(t *testing.T) {
+	m := websocket.NewManager(nil, nil)
+	user := database.User{ID: uuid.New(), Handle: "slow"}
+	connect(t, m, user)
+
+	// The test never reads, so the socket and then the client's queue fill
+	// up. Sends past that point must be dropped rather than wait.
+	payload := map[string]string{"padding": strings.Repeat("x", 4096)}
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 5000; i++ {
+			if err := m.SendToUser(user.ID, websocket.EventServerUpdated, payload); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendToUser blocked on a client that stopped reading")
+	}
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+const (
+	ErrCodeBadRequest = "bad_request"
+	ErrCodeForbidden  = "forbidden"
+	ErrCodeNotFound   = "not_found"
+	ErrCodeInternal   = "internal"
+)
+
+// ClientError is returned by event handlers when the failure should be
+// reported back to the client that sent the event.
+type ClientError struct {
+	Code    string
+	Message string
+}
+
+func (e *ClientError) Error() string {
+	return e.Message
+}
+
+func newClientError(code, message string) *ClientError {
+	return &ClientError{Code: code, Message: message}
+}
+
+func (c *Client) sendError(eventType string, err error) {
+	payload := ErrorEvent{
+		Event:   eventType,
+		Code:    ErrCodeInternal,
+		Message: "failed to handle event",
+	}
+
+	var clientErr *ClientError
+	if errors.As(err, &clientErr) {
+		payload.Code = clientErr.Code
+		payload.Message = clientErr.Message
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("error marshaling error event: %v", err)
+		return
+	}
+
+	c.manager.sendToClient(c, Event{
+		Type:    EventError,
+		Payload: data,
+	})
+}
@@ -9,6 +9,10 @@ type Event struct {
 	Payload json.RawMessage `json:"payload"`
 }
 
+func (e Event) GetType() string {
+	return e.Type
+}
+
 type EventHandler func(event Event, c *Client) error
 
 const (
@@ -21,15 +25,22 @@ const (
 	EventAddedVoiceMember   = "added_voice_member"
 	EventRemoveVoiceMember  = "remove_voice_member"
 	EventRemovedVoiceMember = "removed_voice_member"
+	EventError              = "error"
+
+	EventJoinRequestCreated = "join_request_created"
+	EventJoinRequestDecided = "join_request_decided"
+
+	EventMemberKicked   = "member_kicked"
+	EventMemberBanned   = "member_banned"
+	EventMemberTimedOut = "member_timed_out"
+
+	EventServerUpdated = "server_updated"
 )
 
 type SendMessageEvent struct {
 	Message string `json:"message"`
-	From    string `json:"from"`
-	Handle  string `json:"handle"`
 	Channel string `json:"channel"`
 	Image   string `json:"image"`
-	Avatar  string `json:"avatar"`
 }
 
 type VoiceMemberEvent struct {
@@ -61,6 +72,21 @@ func (r ReturnEventVoiceMember) GetType() string {
 	return r.Type
 }
 
+type ErrorEvent struct {
+	Event   string `json:"event"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ReturnEventError struct {
+	Type    string     `json:"type"`
+	Payload ErrorEvent `json:"payload"`
+}
+
+func (r ReturnEventError) GetType() string {
+	return r.Type
+}
+
 type changeRoomEvent struct {
 	ID string `json:"id"`
 }
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +13,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/api/common"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/permissions"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/redis"
 )
 
@@ -74,85 +77,208 @@ func (m *Manager) routeEvent(event Event, c *Client) error {
 	}
 }
 
+// ChatRoomHandler moves the client into a text channel so it receives the
+// channel's new messages. Only members who can view channels in the
+// channel's server may enter it; an empty ID leaves the current room.
 func ChatRoomHandler(event Event, c *Client) error {
 	var changeRoomEvent changeRoomEvent
 
 	if err := json.Unmarshal(event.Payload, &changeRoomEvent); err != nil {
 		return fmt.Errorf("bad payoad in req: %v", err)
 	}
-	c.chatroom = changeRoomEvent.ID
+
+	if changeRoomEvent.ID == "" {
+		c.manager.setChatroom(c, "", uuid.Nil)
+		return nil
+	}
+
+	channelID, err := uuid.Parse(changeRoomEvent.ID)
+	if err != nil {
+		return newClientError(ErrCodeBadRequest, "invalid UUID format for channel")
+	}
+
+	ctx := context.Background()
+
+	channel, err := c.manager.DB.GetTextChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return newClientError(ErrCodeNotFound, "channel not found")
+		}
+		return fmt.Errorf("failed to get channel: %v", err)
+	}
+
+	allowed, err := c.manager.HasPermission(ctx, c.user.ID, channel.ServerID, permissions.ViewChannels)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %v", err)
+	}
+	if !allowed {
+		return newClientError(ErrCodeForbidden, "not allowed to view this channel")
+	}
+
+	c.manager.setChatroom(c, channel.ID.String(), channel.ServerID)
 	return nil
 }
 
+// setChatroom changes the client's room under the manager lock, which
+// BroadcastMessage and RemoveServerMember read it under.
+func (m *Manager) setChatroom(c *Client, chatroom string, serverID uuid.UUID) {
+	m.Lock()
+	defer m.Unlock()
+
+	c.chatroom = chatroom
+	c.chatServer = serverID
+}
+
+// VoiceRoomHandler records the voice channel the client is in. The channel
+// must belong to the server the client is checked into; an empty ID leaves
+// the current voice room.
 func VoiceRoomHandler(event Event, c *Client) error {
 	var changeRoomEvent changeRoomEvent
 
 	if err := json.Unmarshal(event.Payload, &changeRoomEvent); err != nil {
 		return fmt.Errorf("bad payoad in req: %v", err)
 	}
-	c.voiceroom = changeRoomEvent.ID
-	return nil
-}
 
-func ServerChangeHandler(event Event, c *Client) error {
-	payload := VoiceMemberEvent{
-		User:    c.userID,
-		Channel: c.voiceroom,
-		Server:  c.server,
-		Handle:  c.handle,
+	server, _ := c.manager.location(c)
+
+	if changeRoomEvent.ID == "" {
+		c.manager.setVoiceroom(c, server, "")
+		return nil
 	}
 
-	jsonPayload, err := json.Marshal(payload)
+	channelID, err := uuid.Parse(changeRoomEvent.ID)
 	if err != nil {
-		log.Printf("Error marshaling payload: %v", err)
+		return newClientError(ErrCodeBadRequest, "invalid UUID format for channel")
 	}
 
-	removeEvent := Event{
-		Type:    EventRemoveVoiceMember,
-		Payload: json.RawMessage(jsonPayload),
+	channel, err := c.manager.DB.GetVoiceChannelByID(context.Background(), channelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return newClientError(ErrCodeNotFound, "voice channel not found")
+		}
+		return fmt.Errorf("failed to get voice channel: %v", err)
 	}
 
-	err = RemoveVoiceMember(removeEvent, c)
-	if err != nil {
-		log.Printf("Error removing voice member: %v", err)
+	if server == "" || channel.ServerID.String() != server || !c.manager.setVoiceroom(c, server, channel.ID.String()) {
+		return newClientError(ErrCodeNotFound, "voice channel not found")
 	}
+	return nil
+}
 
+// ServerChangeHandler checks the client into a server so it receives the
+// server's voice presence, leaving the voice room of the server it was in.
+// Only members who can view channels in the server may check in; an empty
+// ID checks out.
+func ServerChangeHandler(event Event, c *Client) error {
 	var changeRoomEvent changeRoomEvent
 
 	if err := json.Unmarshal(event.Payload, &changeRoomEvent); err != nil {
 		return fmt.Errorf("bad payoad in req: %v", err)
 	}
-	c.server = changeRoomEvent.ID
-	log.Printf("Changed Server to %v", changeRoomEvent.ID)
+
+	server := ""
+	if changeRoomEvent.ID != "" {
+		serverID, err := uuid.Parse(changeRoomEvent.ID)
+		if err != nil {
+			return newClientError(ErrCodeBadRequest, "invalid UUID format for server")
+		}
+
+		allowed, err := c.manager.HasPermission(context.Background(), c.user.ID, serverID, permissions.ViewChannels)
+		if err != nil {
+			return fmt.Errorf("failed to check permissions: %v", err)
+		}
+		if !allowed {
+			return newClientError(ErrCodeForbidden, "not allowed to view this server")
+		}
+		server = serverID.String()
+	}
+
+	if err := c.manager.leaveVoice(c); err != nil {
+		log.Printf("Error removing voice member: %v", err)
+	}
+
+	c.manager.setServer(c, server)
+	log.Printf("Changed Server to %v", server)
 	return nil
 }
 
+// location returns the server the client is checked into and its voice
+// room, read under the manager lock they are written under.
+func (m *Manager) location(c *Client) (server, voiceroom string) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return c.server, c.voiceroom
+}
+
+// setServer checks the client into a server under the manager lock, which
+// the voice broadcasts and RemoveServerMember read it under. The client
+// leaves its voice room along with its previous server.
+func (m *Manager) setServer(c *Client, server string) {
+	m.Lock()
+	defer m.Unlock()
+
+	c.server = server
+	c.voiceroom = ""
+}
+
+// setVoiceroom changes the client's voice room under the manager lock. It
+// reports false, leaving the room unchanged, if the client is no longer
+// checked into server, for example because it was removed from it meanwhile.
+func (m *Manager) setVoiceroom(c *Client, server, voiceroom string) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	if c.server != server {
+		return false
+	}
+	c.voiceroom = voiceroom
+	return true
+}
+
 func SendMessage(event Event, c *Client) error {
+	if !c.hasScope(common.ScopeMessagesWrite) {
+		return newClientError(ErrCodeForbidden, "token is missing scope "+common.ScopeMessagesWrite)
+	}
+
 	var chatEvent SendMessageEvent
 	if err := json.Unmarshal(event.Payload, &chatEvent); err != nil {
-		return fmt.Errorf("bad payload in request: %v", err)
+		return newClientError(ErrCodeBadRequest, fmt.Sprintf("bad payload in request: %v", err))
 	}
 
 	channelID, err := uuid.Parse(chatEvent.Channel)
 	if err != nil {
-		return fmt.Errorf("invalid UUID format for chatroom: %v", err)
+		return newClientError(ErrCodeBadRequest, "invalid UUID format for channel")
 	}
 
-	ownerID, err := uuid.Parse(chatEvent.From)
+	ctx := context.Background()
+
+	channel, err := c.manager.DB.GetTextChannelByID(ctx, channelID)
 	if err != nil {
-		return fmt.Errorf("invalid UUID format for chatroom: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return newClientError(ErrCodeNotFound, "channel not found")
+		}
+		return fmt.Errorf("failed to get channel: %v", err)
+	}
+
+	allowed, err := c.manager.HasPermission(ctx, c.user.ID, channel.ServerID, permissions.SendMessages)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %v", err)
+	}
+	if !allowed {
+		return newClientError(ErrCodeForbidden, "not allowed to send messages in this channel")
 	}
 
 	var createParams = database.CreateTextMessageParams{
 		ID:        uuid.New(),
-		OwnerID:   ownerID,
-		ChannelID: channelID,
+		OwnerID:   c.user.ID,
+		ChannelID: channel.ID,
 		Message:   chatEvent.Message,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
 
-	createdMessage, err := c.manager.DB.CreateTextMessage(context.Background(), createParams)
+	createdMessage, err := c.manager.DB.CreateTextMessage(ctx, createParams)
 	if err != nil {
 		return fmt.Errorf("failed to add message to database: %v", err)
 	}
@@ -161,15 +287,27 @@ func SendMessage(event Event, c *Client) error {
 		ID:          createdMessage.ID,
 		ChannelID:   createdMessage.ChannelID,
 		OwnerID:     createdMessage.OwnerID,
-		OwnerHandle: chatEvent.Handle,
-		OwnerImage:  chatEvent.Avatar,
+		OwnerHandle: c.user.Handle,
+		OwnerImage:  c.user.AvatarUrl.String,
+		IsBot:       c.user.IsBot,
 		Message:     createdMessage.Message,
 		Image:       createdMessage.Image.String,
 		CreatedAt:   createdMessage.CreatedAt,
 		UpdatedAt:   createdMessage.UpdatedAt,
 	}
 
-	payload, err := json.Marshal(response)
+	return c.manager.BroadcastMessage(channel.ServerID, response)
+}
+
+// HasPermission reports whether the user holds perm in the server.
+func (m *Manager) HasPermission(ctx context.Context, userID, serverID uuid.UUID, perm permissions.Permission) (bool, error) {
+	return permissions.HasPermission(ctx, m.DB, userID, serverID, perm)
+}
+
+// BroadcastMessage sends a new_message event to every client viewing the
+// message's channel and to every connected bot that is a member of the server.
+func (m *Manager) BroadcastMessage(serverID uuid.UUID, message SimpleMessage) error {
+	payload, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("error marshaling json for response: %v", err)
 	}
@@ -179,21 +317,18 @@ func SendMessage(event Event, c *Client) error {
 		Type:    EventNewMessage,
 	}
 
-	for client := range c.manager.clients {
-		if client.chatroom == c.chatroom {
-			client.egress <- outgoingEvent
-		}
-	}
+	m.send(outgoingEvent, func(client *Client) bool {
+		inRoom := client.chatroom == message.ChannelID.String() && client.chatServer == serverID
+		return inRoom || client.botServers[serverID]
+	})
 	return nil
 }
 
+// AddVoiceMember puts the client's user in a voice channel of the server the
+// client is checked into and tells the server's other clients, leaving the
+// voice room it was in first. The user and handle broadcast are always the
+// client's own.
 func AddVoiceMember(event Event, c *Client) error {
-
-	err := RemoveVoiceMember(event, c)
-	if err != nil {
-		return fmt.Errorf("error removing user prior to add: %v", err)
-	}
-
 	var memberEvent VoiceMemberEvent
 	if err := json.Unmarshal(event.Payload, &memberEvent); err != nil {
 		return fmt.Errorf("bad payload in request: %v", err)
@@ -214,69 +349,94 @@ func AddVoiceMember(event Event, c *Client) error {
 		return fmt.Errorf("invalid UUID format for server: %v", err)
 	}
 
-	var createParams = database.JoinVoiceChannelParams{
-		UserID:    userUUID,
-		ChannelID: channelUUID,
-		ServerID:  serverUUID,
+	if userUUID != c.user.ID {
+		return newClientError(ErrCodeForbidden, "cannot add another user to a voice channel")
 	}
 
-	_, err = c.manager.DB.JoinVoiceChannel(context.Background(), createParams)
-	if err != nil {
-		return fmt.Errorf("failed to add voice room member to database: %v", err)
+	server, _ := c.manager.location(c)
+	if server != serverUUID.String() {
+		return newClientError(ErrCodeForbidden, "change to the server before joining its voice channels")
 	}
 
-	m := ChannelMember{
-		UserID: userUUID,
-		Handle: memberEvent.Handle,
+	channel, err := c.manager.DB.GetVoiceChannelByID(context.Background(), channelUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return newClientError(ErrCodeNotFound, "voice channel not found")
+		}
+		return fmt.Errorf("failed to get voice channel: %v", err)
+	}
+	if channel.ServerID != serverUUID {
+		return newClientError(ErrCodeNotFound, "voice channel not found")
 	}
 
-	response := ChannelMemberExpanded{
-		ChannelMember: m,
-		Channel:       channelUUID,
+	allowed, err := c.manager.HasPermission(context.Background(), c.user.ID, serverUUID, permissions.ConnectVoice)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %v", err)
+	}
+	if !allowed {
+		return newClientError(ErrCodeForbidden, "not allowed to join voice channels in this server")
 	}
 
-	payload, err := json.Marshal(response)
+	err = c.manager.leaveVoice(c)
 	if err != nil {
-		return fmt.Errorf("error marshaling json for response: %v", err)
+		return fmt.Errorf("error removing user prior to add: %v", err)
 	}
 
-	outgoingAdd := Event{
-		Payload: payload,
-		Type:    EventAddedVoiceMember,
+	var createParams = database.JoinVoiceChannelParams{
+		UserID:    c.user.ID,
+		ChannelID: channel.ID,
+		ServerID:  serverUUID,
 	}
 
-	for client := range c.manager.clients {
-		if client.server == memberEvent.Server {
-			client.egress <- outgoingAdd
-		}
+	_, err = c.manager.DB.JoinVoiceChannel(context.Background(), createParams)
+	if err != nil {
+		return fmt.Errorf("failed to add voice room member to database: %v", err)
 	}
 
-	return nil
+	if !c.manager.setVoiceroom(c, server, channel.ID.String()) {
+		return newClientError(ErrCodeForbidden, "change to the server before joining its voice channels")
+	}
+
+	return c.manager.sendVoiceMember(EventAddedVoiceMember, c, server, channel.ID)
 }
 
+// RemoveVoiceMember takes the client's user out of its voice room. It only
+// ever acts on the client's own user in the server the client is checked
+// into, whatever the payload names.
 func RemoveVoiceMember(event Event, c *Client) error {
-	var memberEvent VoiceMemberEvent
-	if err := json.Unmarshal(event.Payload, &memberEvent); err != nil {
-		return fmt.Errorf("bad payload in request: %v", err)
-	}
-	userUUID, err := uuid.Parse(memberEvent.User)
-	if err != nil {
-		return fmt.Errorf("invalid UUID format for user: %v", err)
+	return c.manager.leaveVoice(c)
+}
+
+// leaveVoice clears the client's voice room and tells the server the client
+// is checked into that its user left. It does nothing if the client is not
+// in a voice room.
+func (m *Manager) leaveVoice(c *Client) error {
+	m.Lock()
+	server, voiceroom := c.server, c.voiceroom
+	c.voiceroom = ""
+	m.Unlock()
+
+	if server == "" || voiceroom == "" {
+		return nil
 	}
 
-	channelUUID, err := uuid.Parse(memberEvent.Channel)
+	channelID, err := uuid.Parse(voiceroom)
 	if err != nil {
 		return fmt.Errorf("invalid UUID format for channel: %v", err)
 	}
 
-	m := ChannelMember{
-		UserID: userUUID,
-		Handle: memberEvent.Handle,
-	}
+	return m.sendVoiceMember(EventRemovedVoiceMember, c, server, channelID)
+}
 
+// sendVoiceMember sends a voice presence event about the client's user to
+// every client checked into server.
+func (m *Manager) sendVoiceMember(eventType string, c *Client, server string, channelID uuid.UUID) error {
 	response := ChannelMemberExpanded{
-		ChannelMember: m,
-		Channel:       channelUUID,
+		ChannelMember: ChannelMember{
+			UserID: c.user.ID,
+			Handle: c.user.Handle,
+		},
+		Channel: channelID,
 	}
 
 	payload, err := json.Marshal(response)
@@ -284,20 +444,16 @@ func RemoveVoiceMember(event Event, c *Client) error {
 		return fmt.Errorf("error marshaling json for response: %v", err)
 	}
 
-	outgoingRemove := Event{
+	m.send(Event{
 		Payload: payload,
-		Type:    EventRemovedVoiceMember,
-	}
-
-	for client := range c.manager.clients {
-		if client.server == memberEvent.Server {
-			client.egress <- outgoingRemove
-		}
-	}
+		Type:    eventType,
+	}, func(client *Client) bool {
+		return client.server == server
+	})
 	return nil
 }
 
-func (m *Manager) ServeWs(w http.ResponseWriter, r *http.Request, userId, handle string) {
+func (m *Manager) ServeWs(w http.ResponseWriter, r *http.Request, user database.User, sessionID uuid.UUID, scopes []string) {
 	log.Println("New WebSocket connection attempt")
 
 	conn, err := webSocketUpgrader.Upgrade(w, r, nil)
@@ -308,7 +464,22 @@ func (m *Manager) ServeWs(w http.ResponseWriter, r *http.Request, userId, handle
 
 	log.Println("WebSocket connection established successfully")
 
-	client := NewClient(conn, m, userId, handle)
+	client := NewClient(conn, m, user, sessionID, scopes)
+
+	// Bots do not switch rooms; they receive messages from every server they
+	// have been invited to.
+	if user.IsBot {
+		servers, err := m.DB.GetUserServers(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Failed to load servers for bot %s: %v", user.ID, err)
+		}
+
+		client.botServers = make(map[uuid.UUID]bool, len(servers))
+		for _, server := range servers {
+			client.botServers[server.ServerID] = true
+		}
+	}
+
 	m.addClient(client)
 
 	go client.readMessages()
@@ -323,25 +494,7 @@ func (m *Manager) addClient(client *Client) {
 }
 
 func (m *Manager) removeClient(client *Client) {
-	payload := VoiceMemberEvent{
-		User:    client.userID,
-		Channel: client.voiceroom,
-		Server:  client.server,
-		Handle:  client.handle,
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("Error marshaling payload: %v", err)
-	}
-
-	event := Event{
-		Type:    EventRemoveVoiceMember,
-		Payload: json.RawMessage(jsonPayload),
-	}
-
-	err = RemoveVoiceMember(event, client)
-	if err != nil {
+	if err := m.leaveVoice(client); err != nil {
 		log.Printf("Error removing voice member: %v", err)
 	}
 
@@ -365,6 +518,196 @@ func (m *Manager) removeClient(client *Client) {
 	}
 }
 
+// AddBotServer starts delivering messages from serverID to the live
+// connections of a bot that was just invited to it.
+func (m *Manager) AddBotServer(botID, serverID uuid.UUID) {
+	m.Lock()
+	defer m.Unlock()
+
+	for client := range m.clients {
+		if client.user.ID == botID && client.botServers != nil {
+			client.botServers[serverID] = true
+		}
+	}
+}
+
+// RemoveServerMember stops a user's live connections from receiving traffic
+// for a server they were removed from.
+func (m *Manager) RemoveServerMember(userID, serverID uuid.UUID) {
+	m.Lock()
+	defer m.Unlock()
+
+	for client := range m.clients {
+		if client.user.ID != userID {
+			continue
+		}
+		delete(client.botServers, serverID)
+		if client.chatServer == serverID {
+			client.chatroom = ""
+			client.chatServer = uuid.Nil
+		}
+		if client.server == serverID.String() {
+			client.server = ""
+			client.chatroom = ""
+			client.voiceroom = ""
+		}
+	}
+}
+
+// Online reports which of the given users have at least one live connection.
+func (m *Manager) Online(userIDs ...uuid.UUID) map[uuid.UUID]bool {
+	wanted := make(map[uuid.UUID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		wanted[userID] = true
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	online := make(map[uuid.UUID]bool)
+	for client := range m.clients {
+		if wanted[client.user.ID] {
+			online[client.user.ID] = true
+		}
+	}
+	return online
+}
+
+// SendToUser delivers an event to every live connection of a user.
+func (m *Manager) SendToUser(userID uuid.UUID, eventType string, payload any) error {
+	event, err := newEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	m.send(event, func(client *Client) bool {
+		return client.user.ID == userID
+	})
+	return nil
+}
+
+// SendToPermission delivers an event to every connected member of a server
+// who holds perm there, such as moderators who review join requests.
+func (m *Manager) SendToPermission(ctx context.Context, serverID uuid.UUID, perm permissions.Permission, eventType string, payload any) error {
+	return m.sendToMembers(ctx, serverID, eventType, payload, func(member permissions.Member) bool {
+		return member.Has(perm)
+	})
+}
+
+// SendToServer delivers an event to every connected member of a server.
+func (m *Manager) SendToServer(ctx context.Context, serverID uuid.UUID, eventType string, payload any) error {
+	return m.sendToMembers(ctx, serverID, eventType, payload, func(permissions.Member) bool {
+		return true
+	})
+}
+
+func (m *Manager) sendToMembers(ctx context.Context, serverID uuid.UUID, eventType string, payload any, match func(member permissions.Member) bool) error {
+	event, err := newEvent(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	m.RLock()
+	connected := len(m.clients)
+	m.RUnlock()
+	if connected == 0 {
+		return nil
+	}
+
+	members, err := permissions.GetMembers(ctx, m.DB, serverID)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %v", err)
+	}
+
+	users := make(map[uuid.UUID]bool, len(members))
+	for _, member := range members {
+		users[member.UserID] = match(member)
+	}
+
+	m.send(event, func(client *Client) bool {
+		return users[client.user.ID]
+	})
+	return nil
+}
+
+// send queues event for every matching client. It holds the read lock while
+// sending so removeClient cannot close a client's egress underneath it, and
+// never blocks on a slow client.
+func (m *Manager) send(event Event, match func(client *Client) bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for client := range m.clients {
+		if match(client) {
+			client.deliver(event)
+		}
+	}
+}
+
+// sendToClient queues event for one client if it is still connected.
+func (m *Manager) sendToClient(c *Client, event Event) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if m.clients[c] {
+		c.deliver(event)
+	}
+}
+
+func newEvent(eventType string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("error marshaling json for event: %v", err)
+	}
+	return Event{Type: eventType, Payload: data}, nil
+}
+
+// DisconnectSessions closes every live connection opened from one of the given
+// login sessions. The read loop notices the closed connection and removes the
+// client from the manager.
+func (m *Manager) DisconnectSessions(sessionIDs ...uuid.UUID) {
+	revoked := make(map[uuid.UUID]bool, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		revoked[sessionID] = true
+	}
+
+	m.disconnect("session revoked", func(client *Client) bool {
+		return revoked[client.sessionID]
+	})
+}
+
+// DisconnectUser closes every live connection of a user, such as a bot whose
+// token was reset.
+func (m *Manager) DisconnectUser(userID uuid.UUID, reason string) {
+	m.disconnect(reason, func(client *Client) bool {
+		return client.user.ID == userID
+	})
+}
+
+func (m *Manager) disconnect(reason string, match func(client *Client) bool) {
+	m.RLock()
+	var clients []*Client
+	for client := range m.clients {
+		if match(client) {
+			clients = append(clients, client)
+		}
+	}
+	m.RUnlock()
+
+	for _, client := range clients {
+		closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+		if err := client.connection.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second)); err != nil {
+			log.Printf("Error sending close message to client %s: %v", client.userID, err)
+		}
+
+		if err := client.connection.Close(); err != nil {
+			log.Printf("Error closing client connection: %v", err)
+		}
+
+		log.Printf("Client %s disconnected: %s", client.userID, reason)
+	}
+}
+
 // func checkOrigin(r *http.Request) bool {
 // 	origin := r.Header.Get("Origin")
 
@@ -7,15 +7,17 @@ import (
 )
 
 type SimpleMessage struct {
-	ID          uuid.UUID `json:"id"`
-	OwnerID     uuid.UUID `json:"owner_id"`
-	OwnerHandle string    `json:"handle"`
-	OwnerImage  string    `json:"owner_image"`
-	ChannelID   uuid.UUID `json:"channel_id"`
-	Message     string    `json:"message"`
-	Image       string    `json:"image"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	OwnerID     uuid.UUID  `json:"owner_id"`
+	OwnerHandle string     `json:"handle"`
+	OwnerImage  string     `json:"owner_image"`
+	IsBot       bool       `json:"is_bot"`
+	ChannelID   uuid.UUID  `json:"channel_id"`
+	Message     string     `json:"message"`
+	Image       string     `json:"image"`
+	WebhookID   *uuid.UUID `json:"webhook_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type ChannelMemberExpanded struct {
@@ -27,3 +29,10 @@ type ChannelMember struct {
 	UserID uuid.UUID `json:"user_id"`
 	Handle string    `json:"handle"`
 }
+
+// ModerationEvent tells a member that a moderator acted on them in a server.
+type ModerationEvent struct {
+	ServerID  uuid.UUID  `json:"server_id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -17,6 +17,9 @@ import (
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/routes"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/api/v1/handlers"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/database"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/jwtkeys"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/mailer"
+	"github.com/jimmyvallejo/gleamspeak-api/internal/oidc"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/redis"
 	"github.com/jimmyvallejo/gleamspeak-api/internal/websocket"
 	"github.com/rs/cors"
@@ -35,6 +38,10 @@ func main() {
 	jwtSecret := os.Getenv("JWT_SECRET")
 	awsAccess := os.Getenv("AWS_ACCESS_KEY")
 	awsSecret := os.Getenv("AWS_SECRET_KEY")
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:5173"
+	}
 
 	db, err := sql.Open("postgres", dbUrl)
 	if err != nil {
@@ -48,6 +55,31 @@ func main() {
 
 	dbQueries := database.New(db)
 
+	keyRotation := 30 * 24 * time.Hour
+	if rotation := os.Getenv("JWT_KEY_ROTATION"); rotation != "" {
+		keyRotation, err = time.ParseDuration(rotation)
+		if err != nil {
+			log.Fatalf("Invalid JWT_KEY_ROTATION: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jwtKeys, err := jwtkeys.New(ctx, jwtkeys.NewDBStore(db), jwtkeys.Config{
+		Algorithm:   os.Getenv("JWT_SIGNING_ALG"),
+		RotateEvery: keyRotation,
+		// Retired keys must outlive the seven day refresh token.
+		VerifyFor:        8 * 24 * time.Hour,
+		LegacySecret:     jwtSecret,
+		EncryptionSecret: os.Getenv("JWT_KEY_ENCRYPTION_SECRET"),
+	})
+	if err != nil {
+		log.Fatalf("Unable to load JWT signing keys: %v", err)
+	}
+
+	go jwtKeys.Run(ctx, time.Hour)
+
 	rdb, err := redis.NewClient()
 	if err != nil {
 		log.Print("Redis failed to initialize")
@@ -66,23 +98,44 @@ func main() {
 
 	s3Client := s3.NewFromConfig(cfg)
 
+	mail, err := mailer.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to configure mailer: %v", err)
+	}
+
+	providers, err := oidc.ProvidersFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to configure OIDC providers: %v", err)
+	}
+
+	loginLimits, err := handlers.LoginLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to configure login limits: %v", err)
+	}
+
+	trustedProxies, err := handlers.TrustedProxiesFromEnv()
+	if err != nil {
+		log.Fatalf("Unable to configure trusted proxies: %v", err)
+	}
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposedHeaders:   []string{"Retry-After"},
 		AllowCredentials: true,
 	})
 
 	apiCfg := APIConfig{
-		Port:      port,
-		DB:        dbQueries,
-		RDB:       rdb,
-		JwtSecret: jwtSecret,
-		S3:        s3Client,
+		Port:    port,
+		DB:      dbQueries,
+		RDB:     rdb,
+		JWTKeys: jwtKeys,
+		S3:      s3Client,
 	}
 	w := websocket.NewManager(apiCfg.DB, apiCfg.RDB)
-	h := handlers.NewHandlers(apiCfg.DB, apiCfg.RDB, apiCfg.JwtSecret, apiCfg.S3, w)
-	m := middleware.NewMiddleware(apiCfg.DB, apiCfg.RDB, apiCfg.JwtSecret)
+	h := handlers.NewHandlers(handlers.NewStore(db), apiCfg.RDB, apiCfg.JWTKeys, apiCfg.S3, w, mail, appURL, providers, loginLimits, trustedProxies)
+	m := middleware.NewMiddleware(apiCfg.DB, apiCfg.RDB, apiCfg.JWTKeys)
 
 	apiCfg.Handlers = h
 
@@ -109,10 +162,12 @@ func main() {
 
 	log.Println("Shutdown signal received, exiting...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server shutdown error: %v", err)
 	}
 }
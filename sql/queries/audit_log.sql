@@ -0,0 +1,50 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (
+        id,
+        server_id,
+        actor_id,
+        action,
+        target_type,
+        target_id,
+        before,
+        after,
+        reason,
+        created_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: GetServerAuditLog :many
+-- The server's audit log, newest first, optionally narrowed to one actor,
+-- one action and a time window. Pages continue after the previous page's
+-- last timestamp and id.
+SELECT a.*,
+    u.handle AS actor_handle
+FROM audit_log a
+    LEFT JOIN users u ON u.id = a.actor_id
+WHERE a.server_id = sqlc.arg(server_id)
+    AND (
+        sqlc.narg(actor_id)::UUID IS NULL
+        OR a.actor_id = sqlc.narg(actor_id)
+    )
+    AND (
+        sqlc.narg(action)::TEXT IS NULL
+        OR a.action = sqlc.narg(action)
+    )
+    AND (
+        sqlc.narg(since)::TIMESTAMP IS NULL
+        OR a.created_at >= sqlc.narg(since)
+    )
+    AND (
+        sqlc.narg(until)::TIMESTAMP IS NULL
+        OR a.created_at < sqlc.narg(until)
+    )
+    AND (
+        sqlc.narg(before_created_at)::TIMESTAMP IS NULL
+        OR (a.created_at, a.id) < (
+            sqlc.narg(before_created_at)::TIMESTAMP,
+            sqlc.arg(before_id)::UUID
+        )
+    )
+ORDER BY a.created_at DESC,
+    a.id DESC
+LIMIT sqlc.arg(page_size);
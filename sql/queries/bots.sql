@@ -0,0 +1,19 @@
+-- name: CreateBotUser :one
+INSERT INTO users (
+        id,
+        email,
+        handle,
+        is_verified,
+        is_bot,
+        bot_owner_id,
+        created_at,
+        updated_at
+    )
+VALUES ($1, $2, $3, TRUE, TRUE, $4, $5, $6)
+RETURNING *;
+-- name: GetOwnerBots :many
+SELECT *
+FROM users
+WHERE bot_owner_id = $1
+    AND is_bot = TRUE
+ORDER BY created_at ASC;
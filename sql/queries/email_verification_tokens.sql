@@ -0,0 +1,17 @@
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (
+        token_hash,
+        user_id,
+        email,
+        expires_at,
+        created_at
+    )
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = $2
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > $2
+RETURNING *;
@@ -0,0 +1,79 @@
+-- name: CreateInvite :one
+INSERT INTO invites (
+        code,
+        server_id,
+        creator_id,
+        max_uses,
+        expires_at,
+        created_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+-- name: GetServerInvites :many
+SELECT *
+FROM invites
+WHERE server_id = $1
+ORDER BY created_at DESC;
+-- name: GetInviteByCode :one
+SELECT *
+FROM invites
+WHERE code = $1;
+-- name: RevokeInvite :execrows
+UPDATE invites
+SET revoked_at = $3
+WHERE code = $1
+    AND server_id = $2
+    AND revoked_at IS NULL;
+-- name: JoinServerWithInvite :one
+-- Consumes one use of a live invite and adds the member in one statement,
+-- so a failed join never counts against max_uses. The server row is locked
+-- and must be below max_members, so concurrent joins cannot overrun the cap.
+WITH capacity AS (
+    SELECT servers.id
+    FROM servers
+    WHERE servers.id = (
+            SELECT invites.server_id
+            FROM invites
+            WHERE invites.code = $1
+                AND invites.revoked_at IS NULL
+                AND (
+                    invites.expires_at IS NULL
+                    OR invites.expires_at > $3
+                )
+                AND (
+                    invites.max_uses IS NULL
+                    OR invites.uses < invites.max_uses
+                )
+        )
+        AND (
+            servers.max_members IS NULL
+            OR COALESCE(servers.member_count, 0) < servers.max_members
+        )
+    FOR UPDATE
+),
+invite AS (
+    UPDATE invites
+    SET uses = uses + 1
+    WHERE code = $1
+        AND server_id IN (
+            SELECT id
+            FROM capacity
+        )
+        AND revoked_at IS NULL
+        AND (
+            expires_at IS NULL
+            OR expires_at > $3
+        )
+        AND (
+            max_uses IS NULL
+            OR uses < max_uses
+        )
+    RETURNING code,
+        server_id
+)
+INSERT INTO user_servers (user_id, server_id, invite_code)
+SELECT $2,
+    invite.server_id,
+    invite.code
+FROM invite
+RETURNING *;
@@ -0,0 +1,83 @@
+-- name: CreateJoinRequest :one
+INSERT INTO join_requests (id, server_id, user_id, message, created_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+-- name: GetPendingJoinRequests :many
+SELECT jr.id,
+    jr.server_id,
+    jr.user_id,
+    jr.message,
+    jr.created_at,
+    u.handle,
+    u.avatar_url
+FROM join_requests jr
+    JOIN users u ON u.id = jr.user_id
+WHERE jr.server_id = $1
+    AND jr.status = 'pending'
+ORDER BY jr.created_at ASC;
+-- name: ApproveJoinRequest :one
+-- Marks the request approved and adds the applicant in one statement. A full
+-- server leaves the request pending. Applicants who already joined some
+-- other way are approved without being added again.
+WITH capacity AS (
+    SELECT servers.id
+    FROM servers
+    WHERE servers.id = $2
+        AND (
+            servers.max_members IS NULL
+            OR COALESCE(servers.member_count, 0) < servers.max_members
+        )
+        AND EXISTS (
+            SELECT 1
+            FROM join_requests r
+            WHERE r.id = $1
+                AND r.server_id = $2
+                AND r.status = 'pending'
+                AND NOT EXISTS (
+                    SELECT 1
+                    FROM user_servers us
+                    WHERE us.user_id = r.user_id
+                        AND us.server_id = r.server_id
+                )
+        )
+    FOR UPDATE
+),
+approved AS (
+    UPDATE join_requests
+    SET status = 'approved',
+        decided_by = $3,
+        decided_at = $4
+    WHERE join_requests.id = $1
+        AND join_requests.server_id = $2
+        AND status = 'pending'
+        AND (
+            join_requests.server_id IN (
+                SELECT id
+                FROM capacity
+            )
+            OR EXISTS (
+                SELECT 1
+                FROM user_servers us
+                WHERE us.user_id = join_requests.user_id
+                    AND us.server_id = join_requests.server_id
+            )
+        )
+    RETURNING *
+),
+joined AS (
+    INSERT INTO user_servers (user_id, server_id)
+    SELECT user_id,
+        server_id
+    FROM approved ON CONFLICT DO NOTHING
+)
+SELECT *
+FROM approved;
+-- name: RejectJoinRequest :one
+UPDATE join_requests
+SET status = 'rejected',
+    decided_by = $3,
+    decided_at = $4
+WHERE id = $1
+    AND server_id = $2
+    AND status = 'pending'
+RETURNING *;
@@ -0,0 +1,84 @@
+-- name: RemoveServerMember :execrows
+DELETE FROM user_servers
+WHERE user_id = $1
+    AND server_id = $2;
+-- name: BanMember :one
+-- Records the ban, removes the membership and closes any pending join
+-- request in one statement.
+WITH ban AS (
+    INSERT INTO server_bans (
+            server_id,
+            user_id,
+            reason,
+            banned_by,
+            expires_at,
+            created_at
+        )
+    VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (server_id, user_id) DO
+    UPDATE
+    SET reason = EXCLUDED.reason,
+        banned_by = EXCLUDED.banned_by,
+        expires_at = EXCLUDED.expires_at,
+        created_at = EXCLUDED.created_at
+    RETURNING *
+),
+removed AS (
+    DELETE FROM user_servers
+    WHERE user_servers.user_id = $2
+        AND user_servers.server_id = $1
+    RETURNING user_servers.user_id
+),
+rejected AS (
+    UPDATE join_requests
+    SET status = 'rejected',
+        decided_by = $4,
+        decided_at = $6
+    WHERE join_requests.server_id = $1
+        AND join_requests.user_id = $2
+        AND join_requests.status = 'pending'
+)
+SELECT ban.server_id,
+    ban.user_id,
+    ban.reason,
+    ban.banned_by,
+    ban.expires_at,
+    ban.created_at,
+    (
+        SELECT COUNT(*)
+        FROM removed
+    ) AS removed_members
+FROM ban;
+-- name: UnbanMember :execrows
+DELETE FROM server_bans
+WHERE server_id = $1
+    AND user_id = $2;
+-- name: GetActiveBan :one
+SELECT *
+FROM server_bans
+WHERE server_id = $1
+    AND user_id = $2
+    AND (
+        expires_at IS NULL
+        OR expires_at > $3
+    );
+-- name: GetServerBans :many
+SELECT b.server_id,
+    b.user_id,
+    b.reason,
+    b.banned_by,
+    b.expires_at,
+    b.created_at,
+    u.handle
+FROM server_bans b
+    JOIN users u ON u.id = b.user_id
+WHERE b.server_id = $1
+    AND (
+        b.expires_at IS NULL
+        OR b.expires_at > $2
+    )
+ORDER BY b.created_at DESC;
+-- name: SetMemberMutedUntil :execrows
+UPDATE user_servers
+SET muted_until = $3
+WHERE user_id = $1
+    AND server_id = $2;
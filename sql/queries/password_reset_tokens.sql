@@ -0,0 +1,21 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+        token_hash,
+        user_id,
+        expires_at,
+        created_at
+    )
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+-- name: UsePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = $2
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > $2
+RETURNING *;
+-- name: InvalidateUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = $2
+WHERE user_id = $1
+    AND used_at IS NULL;
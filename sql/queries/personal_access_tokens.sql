@@ -0,0 +1,32 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (
+        id,
+        user_id,
+        name,
+        token_hash,
+        scopes,
+        expires_at,
+        created_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+-- name: GetPersonalAccessTokenByHash :one
+SELECT *
+FROM personal_access_tokens
+WHERE token_hash = $1;
+-- name: GetUserPersonalAccessTokens :many
+SELECT *
+FROM personal_access_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = $2
+WHERE id = $1;
+-- name: DeletePersonalAccessToken :execrows
+DELETE FROM personal_access_tokens
+WHERE id = $1
+    AND user_id = $2;
+-- name: DeleteUserPersonalAccessTokens :exec
+DELETE FROM personal_access_tokens
+WHERE user_id = $1;
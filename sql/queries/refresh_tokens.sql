@@ -0,0 +1,26 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (
+        id,
+        family_id,
+        user_id,
+        expires_at,
+        created_at
+    )
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+-- name: GetRefreshTokenByID :one
+SELECT *
+FROM refresh_tokens
+WHERE id = $1;
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET revoked_at = $2,
+    replaced_by = $3
+WHERE id = $1
+    AND revoked_at IS NULL;
+-- name: RevokeRefreshTokenFamily :many
+UPDATE refresh_tokens
+SET revoked_at = $2
+WHERE family_id = $1
+    AND revoked_at IS NULL
+RETURNING id;
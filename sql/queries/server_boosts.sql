@@ -0,0 +1,56 @@
+-- name: BoostServer :one
+-- Records the boost and moves the server to the level its new boost count
+-- reaches in one statement. level_thresholds holds the boosts each level
+-- above 0 needs and level_max_members the member cap of every level.
+WITH boost AS (
+    INSERT INTO server_boosts (server_id, user_id, created_at)
+    VALUES (
+            sqlc.arg(server_id),
+            sqlc.arg(user_id),
+            sqlc.arg(created_at)
+        ) ON CONFLICT DO NOTHING
+    RETURNING server_id
+)
+UPDATE servers
+SET boost_count = servers.boost_count + 1,
+    server_level = (
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= servers.boost_count + 1
+    ),
+    max_members = (sqlc.arg(level_max_members)::INT []) [(
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= servers.boost_count + 1
+    ) + 1]
+FROM boost
+WHERE servers.id = boost.server_id
+RETURNING servers.*;
+-- name: RemoveServerBoost :one
+-- Withdraws the user's boost and recomputes the server's level the same way
+-- BoostServer does. Lowering the cap never removes existing members.
+WITH removed AS (
+    DELETE FROM server_boosts
+    WHERE server_boosts.server_id = sqlc.arg(server_id)
+        AND server_boosts.user_id = sqlc.arg(user_id)
+    RETURNING server_boosts.server_id
+)
+UPDATE servers
+SET boost_count = GREATEST(servers.boost_count - 1, 0),
+    server_level = (
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= GREATEST(servers.boost_count - 1, 0)
+    ),
+    max_members = (sqlc.arg(level_max_members)::INT []) [(
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= GREATEST(servers.boost_count - 1, 0)
+    ) + 1]
+FROM removed
+WHERE servers.id = removed.server_id
+RETURNING servers.*;
+-- name: GetUserBoostedServerIDs :many
+SELECT server_id
+FROM server_boosts
+WHERE user_id = $1;
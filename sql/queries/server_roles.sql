@@ -0,0 +1,92 @@
+-- name: CreateServerRole :one
+INSERT INTO server_roles (
+        id,
+        server_id,
+        name,
+        permissions,
+        position,
+        is_default,
+        created_at,
+        updated_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+-- name: GetServerRoles :many
+SELECT *
+FROM server_roles
+WHERE server_id = $1
+ORDER BY position DESC,
+    created_at ASC;
+-- name: GetServerRole :one
+SELECT *
+FROM server_roles
+WHERE id = $1
+    AND server_id = $2;
+-- name: UpdateServerRole :one
+UPDATE server_roles
+SET name = $3,
+    permissions = $4,
+    position = $5,
+    updated_at = $6
+WHERE id = $1
+    AND server_id = $2
+RETURNING *;
+-- name: DeleteServerRole :execrows
+DELETE FROM server_roles
+WHERE id = $1
+    AND server_id = $2
+    AND is_default = FALSE;
+-- name: AddMemberRole :exec
+INSERT INTO member_roles (user_id, server_id, role_id)
+VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;
+-- name: RemoveMemberRole :execrows
+DELETE FROM member_roles
+WHERE user_id = $1
+    AND server_id = $2
+    AND role_id = $3;
+-- name: GetMemberRoles :many
+SELECT r.*
+FROM server_roles r
+    JOIN member_roles mr ON mr.role_id = r.id
+WHERE mr.user_id = $1
+    AND mr.server_id = $2
+ORDER BY r.position DESC;
+-- name: GetMemberPermissions :one
+SELECT s.owner_id,
+    us.muted_until,
+    COALESCE(BIT_OR(r.permissions), 0)::BIGINT AS permissions,
+    COALESCE(MAX(r.position), 0)::INTEGER AS top_position
+FROM user_servers us
+    JOIN servers s ON s.id = us.server_id
+    LEFT JOIN member_roles mr ON mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+    LEFT JOIN server_roles r ON r.server_id = us.server_id
+    AND (
+        r.id = mr.role_id
+        OR r.is_default
+    )
+WHERE us.user_id = $1
+    AND us.server_id = $2
+GROUP BY s.owner_id,
+    us.muted_until;
+-- name: GetServerMemberPermissions :many
+-- The effective permissions of every member of a server, resolved the same
+-- way as GetMemberPermissions.
+SELECT us.user_id,
+    s.owner_id,
+    us.muted_until,
+    COALESCE(BIT_OR(r.permissions), 0)::BIGINT AS permissions,
+    COALESCE(MAX(r.position), 0)::INTEGER AS top_position
+FROM user_servers us
+    JOIN servers s ON s.id = us.server_id
+    LEFT JOIN member_roles mr ON mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+    LEFT JOIN server_roles r ON r.server_id = us.server_id
+    AND (
+        r.id = mr.role_id
+        OR r.is_default
+    )
+WHERE us.server_id = $1
+GROUP BY us.user_id,
+    s.owner_id,
+    us.muted_until;
@@ -0,0 +1,15 @@
+-- name: GetServerTags :many
+SELECT tag
+FROM server_tags
+WHERE server_id = $1
+ORDER BY tag;
+-- name: SetServerTags :exec
+-- Replaces the server's tags with the given set in one statement.
+WITH removed AS (
+    DELETE FROM server_tags
+    WHERE server_tags.server_id = sqlc.arg(server_id)
+        AND NOT (server_tags.tag = ANY(sqlc.arg(tags)::TEXT []))
+)
+INSERT INTO server_tags (server_id, tag)
+SELECT sqlc.arg(server_id),
+    unnest(sqlc.arg(tags)::TEXT []) ON CONFLICT DO NOTHING;
@@ -13,6 +13,113 @@ RETURNING *;
 DELETE FROM servers
 WHERE id = $1;
 
+-- name: DiscoverServers :many
+-- Public servers matching the optional search, tag and channel language,
+-- ordered by the chosen sort key and then id. Pages continue after the
+-- previous page's last key and id.
+WITH candidates AS (
+    SELECT s.id,
+        s.server_name,
+        s.description,
+        s.icon_url,
+        s.banner_url,
+        s.member_count,
+        s.created_at,
+        s.updated_at,
+        u.handle,
+        u.avatar_url,
+        (
+            CASE
+                sqlc.arg(sort)::TEXT
+                WHEN 'members' THEN COALESCE(s.member_count, 0)
+                WHEN 'activity' THEN EXTRACT(
+                    EPOCH
+                    FROM COALESCE(
+                            (
+                                SELECT MAX(tm.created_at)
+                                FROM text_messages tm
+                                    JOIN text_channels tc ON tc.id = tm.channel_id
+                                WHERE tc.server_id = s.id
+                            ),
+                            s.created_at
+                        )
+                )
+                ELSE EXTRACT(
+                    EPOCH
+                    FROM s.created_at
+                )
+            END
+        )::DOUBLE PRECISION AS sort_key,
+        COALESCE(
+            (
+                SELECT array_agg(
+                        t.tag
+                        ORDER BY t.tag
+                    )
+                FROM server_tags t
+                WHERE t.server_id = s.id
+            ),
+            '{}'
+        )::TEXT [] AS tags
+    FROM servers s
+        INNER JOIN users u ON s.owner_id = u.id
+    WHERE s.is_public = TRUE
+        AND (
+            sqlc.arg(query)::TEXT = ''
+            OR to_tsvector(
+                'simple',
+                s.server_name || ' ' || COALESCE(s.description, '')
+            ) @@ websearch_to_tsquery('simple', sqlc.arg(query)::TEXT)
+        )
+        AND (
+            sqlc.arg(tag)::TEXT = ''
+            OR EXISTS (
+                SELECT 1
+                FROM server_tags t
+                WHERE t.server_id = s.id
+                    AND t.tag = sqlc.arg(tag)::TEXT
+            )
+        )
+        AND (
+            sqlc.arg(language)::TEXT = ''
+            OR EXISTS (
+                SELECT 1
+                FROM text_channels tc
+                    JOIN languages l ON l.id = tc.language_id
+                WHERE tc.server_id = s.id
+                    AND l.language = sqlc.arg(language)::TEXT
+            )
+            OR EXISTS (
+                SELECT 1
+                FROM voice_channels vc
+                    JOIN languages l ON l.id = vc.language_id
+                WHERE vc.server_id = s.id
+                    AND l.language = sqlc.arg(language)::TEXT
+            )
+        )
+)
+SELECT id,
+    server_name,
+    description,
+    icon_url,
+    banner_url,
+    member_count,
+    created_at,
+    updated_at,
+    handle,
+    avatar_url,
+    sort_key,
+    tags
+FROM candidates
+WHERE sqlc.narg(after_key)::DOUBLE PRECISION IS NULL
+    OR (sort_key, id) < (
+        sqlc.narg(after_key)::DOUBLE PRECISION,
+        sqlc.arg(after_id)::UUID
+    )
+ORDER BY sort_key DESC,
+    id DESC
+LIMIT sqlc.arg(page_size);
+
 -- name: GetOneServerByID :one
 SELECT *
 FROM servers
@@ -37,13 +144,6 @@ FROM servers s
 WHERE s.is_public = TRUE
 ORDER BY s.created_at DESC
 LIMIT 10;
--- name: UpdateServerMemberCount :one
-UPDATE servers
-SET member_count = $2
-WHERE id = $1
-RETURNING id,
-    server_name,
-    member_count;
 -- name: UpdateServerIconByID :one
 UPDATE servers
 SET icon_url = $2
@@ -64,4 +164,79 @@ SET server_name = $1,
     description = $2,
     updated_at = $3
 WHERE id = $4
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+-- name: TransferServerOwnership :one
+-- Hands the server to an existing member and gives the previous owner the
+-- highest non-default role, so both changes land or neither does.
+WITH transferred AS (
+    UPDATE servers
+    SET owner_id = sqlc.arg(new_owner_id),
+        updated_at = sqlc.arg(updated_at)
+    WHERE servers.id = sqlc.arg(id)
+        AND servers.owner_id = sqlc.arg(owner_id)
+        AND EXISTS (
+            SELECT 1
+            FROM user_servers
+            WHERE user_servers.user_id = sqlc.arg(new_owner_id)
+                AND user_servers.server_id = sqlc.arg(id)
+        )
+    RETURNING *
+),
+demoted AS (
+    INSERT INTO member_roles (user_id, server_id, role_id)
+    SELECT sqlc.arg(owner_id),
+        r.server_id,
+        r.id
+    FROM server_roles r
+        JOIN transferred t ON t.id = r.server_id
+    WHERE r.is_default = FALSE
+    ORDER BY r.position DESC
+    LIMIT 1 ON CONFLICT DO NOTHING
+)
+SELECT *
+FROM transferred;
+-- name: ReconcileServerCounts :many
+-- Recounts every server's members and boosts from user_servers and
+-- server_boosts and repairs the servers whose stored counts drifted. Their
+-- level and cap follow the recounted boosts, as in BoostServer.
+WITH actual AS (
+    SELECT s.id,
+        s.member_count AS stored_members,
+        s.boost_count AS stored_boosts,
+        (
+            SELECT COUNT(*)
+            FROM user_servers us
+            WHERE us.server_id = s.id
+        )::INT AS members,
+        (
+            SELECT COUNT(*)
+            FROM server_boosts b
+            WHERE b.server_id = s.id
+        )::INT AS boosts
+    FROM servers s
+)
+UPDATE servers
+SET member_count = actual.members,
+    boost_count = actual.boosts,
+    server_level = (
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= actual.boosts
+    ),
+    max_members = (sqlc.arg(level_max_members)::INT []) [(
+        SELECT COUNT(*)::INT
+        FROM unnest(sqlc.arg(level_thresholds)::INT []) AS threshold
+        WHERE threshold <= actual.boosts
+    ) + 1]
+FROM actual
+WHERE servers.id = actual.id
+    AND (
+        servers.member_count IS DISTINCT FROM actual.members
+        OR servers.boost_count <> actual.boosts
+    )
+RETURNING servers.id,
+    servers.server_name,
+    actual.stored_members,
+    actual.members,
+    actual.stored_boosts,
+    actual.boosts;
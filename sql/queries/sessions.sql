@@ -0,0 +1,39 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+        id,
+        user_id,
+        user_agent,
+        ip_address,
+        created_at,
+        last_used_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+-- name: GetSessionByID :one
+SELECT *
+FROM sessions
+WHERE id = $1;
+-- name: GetActiveUserSessions :many
+SELECT *
+FROM sessions
+WHERE user_id = $1
+    AND revoked_at IS NULL
+ORDER BY last_used_at DESC;
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = $2
+WHERE id = $1;
+-- name: RevokeSession :one
+UPDATE sessions
+SET revoked_at = $3
+WHERE id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL
+RETURNING *;
+-- name: RevokeUserSessionsExcept :many
+UPDATE sessions
+SET revoked_at = $3
+WHERE user_id = $1
+    AND id <> $2
+    AND revoked_at IS NULL
+RETURNING id;
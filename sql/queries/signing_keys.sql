@@ -0,0 +1,22 @@
+-- name: CreateSigningKey :one
+INSERT INTO signing_keys (id, algorithm, private_key, created_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+-- name: GetUnexpiredSigningKeys :many
+SELECT *
+FROM signing_keys
+WHERE expires_at IS NULL
+    OR expires_at > $1
+ORDER BY created_at ASC;
+-- name: RetireSigningKeys :exec
+UPDATE signing_keys
+SET retired_at = $1,
+    expires_at = $2
+WHERE retired_at IS NULL
+    AND id <> $3;
+-- name: DeleteExpiredSigningKeys :exec
+DELETE FROM signing_keys
+WHERE expires_at <= $1;
+-- name: LockSigningKeys :exec
+-- Serializes key rotation across instances until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtext('signing_keys'));
@@ -16,4 +16,7 @@ WHERE id = $1;
 
 -- name: GetServerTextChannels :many
 SELECT * FROM text_channels
-WHERE server_id = $1;
\ No newline at end of file
+WHERE server_id = $1;
+-- name: GetTextChannelByID :one
+SELECT * FROM text_channels
+WHERE id = $1;
@@ -10,6 +10,21 @@ INSERT INTO text_messages (
     )
 VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
+-- name: CreateWebhookMessage :one
+INSERT INTO text_messages (
+        id,
+        owner_id,
+        channel_id,
+        message,
+        image,
+        webhook_id,
+        author_name,
+        author_avatar,
+        created_at,
+        updated_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
 -- name: GetChannelTextMessages :many
 SELECT t.id,
     t.owner_id,
@@ -18,8 +33,12 @@ SELECT t.id,
     t.image,
     t.created_at,
     t.updated_at,
+    t.webhook_id,
+    t.author_name,
+    t.author_avatar,
     u.handle,
-    u.avatar_url
+    u.avatar_url,
+    u.is_bot
 FROM text_messages t
     INNER JOIN users u ON t.owner_id = u.id
 WHERE t.channel_id = $1;
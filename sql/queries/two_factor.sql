@@ -0,0 +1,39 @@
+-- name: UpsertUserTOTP :one
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, $3) ON CONFLICT (user_id) DO
+UPDATE
+SET secret = EXCLUDED.secret,
+    last_used_step = 0,
+    created_at = EXCLUDED.created_at
+WHERE user_totp.enabled_at IS NULL
+RETURNING *;
+-- name: GetUserTOTP :one
+SELECT *
+FROM user_totp
+WHERE user_id = $1;
+-- name: EnableUserTOTP :one
+UPDATE user_totp
+SET enabled_at = $2
+WHERE user_id = $1
+    AND enabled_at IS NULL
+RETURNING *;
+-- name: UpdateUserTOTPLastUsedStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1
+    AND last_used_step < $2;
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at)
+VALUES ($1, $2, $3, $4);
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes
+SET used_at = $3
+WHERE user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM user_recovery_codes
+WHERE user_id = $1;
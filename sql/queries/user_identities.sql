@@ -0,0 +1,22 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+        id,
+        user_id,
+        provider,
+        subject,
+        email,
+        created_at,
+        last_login_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+-- name: GetUserIdentity :one
+SELECT *
+FROM user_identities
+WHERE provider = $1
+    AND subject = $2;
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET last_login_at = $2,
+    email = $3
+WHERE id = $1;
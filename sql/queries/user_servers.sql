@@ -1,8 +1,48 @@
 -- name: CreateUserServer :one
-INSERT INTO user_servers (user_id, server_id, role)
-VALUES ($1, $2, $3)
+INSERT INTO user_servers (user_id, server_id)
+VALUES ($1, $2)
 RETURNING *;
 
+-- name: GetServerMembers :many
+-- Lists a server's members ordered by handle then id, starting after the
+-- cursor. An empty prefix matches every handle and a null role every member.
+SELECT u.id,
+    u.handle,
+    u.avatar_url,
+    u.is_bot,
+    us.muted_until,
+    COALESCE(
+        array_agg(mr.role_id) FILTER (
+            WHERE mr.role_id IS NOT NULL
+        ),
+        '{}'
+    )::UUID [] AS role_ids
+FROM user_servers us
+    JOIN users u ON u.id = us.user_id
+    LEFT JOIN member_roles mr ON mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+WHERE us.server_id = sqlc.arg(server_id)
+    AND (u.handle, u.id) > (
+        sqlc.arg(after_handle)::TEXT,
+        sqlc.arg(after_id)::UUID
+    )
+    AND lower(u.handle) LIKE lower(sqlc.arg(handle_prefix)::TEXT) || '%'
+    AND (
+        sqlc.narg(role_id)::UUID IS NULL
+        OR EXISTS (
+            SELECT 1
+            FROM member_roles f
+            WHERE f.user_id = us.user_id
+                AND f.server_id = us.server_id
+                AND f.role_id = sqlc.narg(role_id)
+        )
+    )
+GROUP BY u.id,
+    us.muted_until
+ORDER BY u.handle,
+    u.id
+LIMIT sqlc.arg(page_size);
+
 -- name: GetUserServers :many
 SELECT s.id AS server_id,
     s.server_name,
@@ -15,6 +55,7 @@ SELECT s.id AS server_id,
     s.server_level,
     s.max_members,
     s.invite_code,
+    s.boost_count,
     s.created_at AS server_created_at,
     s.updated_at AS server_updated_at
 FROM user_servers us
@@ -22,6 +63,26 @@ FROM user_servers us
 WHERE us.user_id = $1
 ORDER BY s.server_name ASC;
 
+-- name: JoinServerMember :one
+-- Adds the member while the server is below max_members. Locking the server
+-- row makes concurrent joins wait their turn and re-check the cap against
+-- the count the user_servers trigger keeps, so the cap cannot be overrun.
+WITH capacity AS (
+    SELECT id
+    FROM servers
+    WHERE id = sqlc.arg(server_id)
+        AND (
+            max_members IS NULL
+            OR COALESCE(member_count, 0) < max_members
+        )
+    FOR UPDATE
+)
+INSERT INTO user_servers (user_id, server_id)
+SELECT sqlc.arg(user_id),
+    capacity.id
+FROM capacity
+RETURNING *;
+
 -- name: GetUserServer :one
 SELECT * FROM user_servers
 WHERE user_id = $1 AND server_id = $2;
@@ -29,7 +29,11 @@ FROM users
 WHERE email = $1;
 -- name: UpdateUserByID :one
 UPDATE users
-SET email = $1,
+SET is_verified = CASE
+        WHEN email = $1 THEN is_verified
+        ELSE FALSE
+    END,
+    email = $1,
     handle = $2,
     first_name = $3,
     last_name = $4,
@@ -41,4 +45,17 @@ RETURNING *;
 UPDATE users
 SET avatar_url = $1
 WHERE id = $2
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+-- name: SetUserVerified :one
+UPDATE users
+SET is_verified = TRUE,
+    updated_at = $2
+WHERE id = $1
+    AND email = $3
+RETURNING *;
+-- name: UpdateUserPassword :one
+UPDATE users
+SET password = $2,
+    updated_at = $3
+WHERE id = $1
+RETURNING *;
@@ -18,9 +18,20 @@ INSERT INTO voice_channel_members (
     )
 VALUES ($1, $2, $3)
 RETURNING *;
+-- name: GetVoiceChannelByID :one
+SELECT * FROM voice_channels
+WHERE id = $1;
 -- name: LeaveVoiceChannelByUser :exec
 DELETE FROM voice_channel_members
 WHERE user_id = $1;
+-- name: LeaveServerVoiceChannels :exec
+DELETE FROM voice_channel_members
+WHERE user_id = $1
+    AND server_id = $2;
+-- name: GetUserVoiceChannelMemberships :many
+SELECT *
+FROM voice_channel_members
+WHERE user_id = $1;
 -- name: GetServerVoiceChannels :many
 SELECT 
     vc.id AS channel_id,
@@ -0,0 +1,39 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+        id,
+        channel_id,
+        creator_id,
+        name,
+        avatar_url,
+        token_hash,
+        created_at,
+        updated_at
+    )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+-- name: GetWebhookByID :one
+SELECT *
+FROM webhooks
+WHERE id = $1;
+-- name: GetWebhookByToken :one
+SELECT w.*,
+    c.server_id
+FROM webhooks w
+    INNER JOIN text_channels c ON c.id = w.channel_id
+WHERE w.id = $1
+    AND w.token_hash = $2;
+-- name: GetChannelWebhooks :many
+SELECT *
+FROM webhooks
+WHERE channel_id = $1
+ORDER BY created_at;
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET name = $2,
+    avatar_url = $3,
+    updated_at = $4
+WHERE id = $1
+RETURNING *;
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY,
+    family_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by UUID,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    user_agent TEXT,
+    ip_address TEXT,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+INSERT INTO sessions (id, user_id, created_at, last_used_at, revoked_at)
+SELECT family_id,
+    user_id,
+    MIN(created_at),
+    MAX(created_at),
+    CASE
+        WHEN bool_and(revoked_at IS NOT NULL) THEN MAX(revoked_at)
+    END
+FROM refresh_tokens
+GROUP BY family_id,
+    user_id;
+
+ALTER TABLE refresh_tokens
+ADD CONSTRAINT fk_session FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+-- +goose Down
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_session;
+DROP TABLE IF EXISTS sessions;
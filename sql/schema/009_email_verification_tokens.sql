@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE email_verification_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+-- +goose Down
+DROP TABLE IF EXISTS email_verification_tokens;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_user_recovery_codes_user_code ON user_recovery_codes(user_id, code_hash);
+-- +goose Down
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT,
+    created_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP NOT NULL,
+    UNIQUE (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+-- +goose Down
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN bot_owner_id UUID REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX idx_users_bot_owner_id ON users(bot_owner_id);
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_bot_owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS bot_owner_id,
+    DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,141 @@
+-- +goose Up
+CREATE TABLE server_roles (
+    id UUID PRIMARY KEY,
+    server_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    permissions BIGINT NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_server_roles_server_id ON server_roles(server_id);
+CREATE UNIQUE INDEX idx_server_roles_default ON server_roles(server_id)
+WHERE is_default;
+CREATE TABLE member_roles (
+    user_id UUID NOT NULL,
+    server_id UUID NOT NULL,
+    role_id UUID NOT NULL,
+    PRIMARY KEY (user_id, role_id),
+    FOREIGN KEY (user_id, server_id) REFERENCES user_servers(user_id, server_id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES server_roles(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_member_roles_server_id ON member_roles(server_id);
+-- Every server gets a default role that all members hold implicitly. Its
+-- permissions (7680) are view channels, send messages, connect voice and
+-- create invite.
+INSERT INTO server_roles (
+        id,
+        server_id,
+        name,
+        permissions,
+        position,
+        is_default,
+        created_at,
+        updated_at
+    )
+SELECT gen_random_uuid(),
+    s.id,
+    '@everyone',
+    7680,
+    0,
+    TRUE,
+    NOW(),
+    NOW()
+FROM servers s;
+-- Replace the old role strings with real roles: admin (administrator) and
+-- moderator (manage messages, kick, timeout and mention everyone).
+INSERT INTO server_roles (
+        id,
+        server_id,
+        name,
+        permissions,
+        position,
+        created_at,
+        updated_at
+    )
+SELECT gen_random_uuid(),
+    s.id,
+    'Admin',
+    1,
+    2,
+    NOW(),
+    NOW()
+FROM servers s
+WHERE EXISTS (
+        SELECT 1
+        FROM user_servers us
+        WHERE us.server_id = s.id
+            AND us.role IN ('owner', 'admin')
+    );
+INSERT INTO server_roles (
+        id,
+        server_id,
+        name,
+        permissions,
+        position,
+        created_at,
+        updated_at
+    )
+SELECT gen_random_uuid(),
+    s.id,
+    'Moderator',
+    432,
+    1,
+    NOW(),
+    NOW()
+FROM servers s
+WHERE EXISTS (
+        SELECT 1
+        FROM user_servers us
+        WHERE us.server_id = s.id
+            AND us.role = 'moderator'
+    );
+INSERT INTO member_roles (user_id, server_id, role_id)
+SELECT us.user_id,
+    us.server_id,
+    r.id
+FROM user_servers us
+    JOIN server_roles r ON r.server_id = us.server_id
+    AND NOT r.is_default
+    AND (
+        (
+            r.name = 'Admin'
+            AND us.role IN ('owner', 'admin')
+        )
+        OR (
+            r.name = 'Moderator'
+            AND us.role = 'moderator'
+        )
+    );
+-- The restricted 'bot' role has no replacement. Bots hold @everyone like any
+-- other member, which lets them create invites, and get more only through
+-- roles assigned to them.
+ALTER TABLE user_servers DROP COLUMN role;
+-- +goose Down
+ALTER TABLE user_servers
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+UPDATE user_servers us
+SET role = 'bot'
+FROM users u
+WHERE u.id = us.user_id
+    AND u.is_bot;
+UPDATE user_servers us
+SET role = 'moderator'
+FROM member_roles mr
+    JOIN server_roles r ON r.id = mr.role_id
+WHERE mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+    AND r.name = 'Moderator';
+UPDATE user_servers us
+SET role = 'admin'
+FROM member_roles mr
+    JOIN server_roles r ON r.id = mr.role_id
+WHERE mr.user_id = us.user_id
+    AND mr.server_id = us.server_id
+    AND r.name = 'Admin';
+ALTER TABLE user_servers
+ALTER COLUMN role DROP DEFAULT;
+DROP TABLE IF EXISTS member_roles;
+DROP TABLE IF EXISTS server_roles;
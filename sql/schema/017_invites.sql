@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE invites (
+    code TEXT PRIMARY KEY,
+    server_id UUID NOT NULL,
+    creator_id UUID,
+    max_uses INTEGER,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_invites_server_id ON invites(server_id);
+-- Each server's existing code becomes a permanent invite from its owner.
+INSERT INTO invites (code, server_id, creator_id, created_at)
+SELECT invite_code,
+    id,
+    owner_id,
+    created_at
+FROM servers
+WHERE invite_code IS NOT NULL;
+ALTER TABLE user_servers
+ADD COLUMN invite_code TEXT REFERENCES invites(code) ON DELETE SET NULL;
+-- +goose Down
+ALTER TABLE user_servers DROP COLUMN IF EXISTS invite_code;
+DROP TABLE IF EXISTS invites;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE join_requests (
+    id UUID PRIMARY KEY,
+    server_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    message TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    decided_by UUID,
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT join_requests_status_check CHECK (status IN ('pending', 'approved', 'rejected')),
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (decided_by) REFERENCES users(id) ON DELETE SET NULL
+);
+-- A user can only have one open request per server.
+CREATE UNIQUE INDEX idx_join_requests_pending ON join_requests(server_id, user_id)
+WHERE status = 'pending';
+-- +goose Down
+DROP TABLE IF EXISTS join_requests;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE user_servers
+ADD COLUMN muted_until TIMESTAMP;
+CREATE TABLE server_bans (
+    server_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    reason TEXT,
+    banned_by UUID,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (server_id, user_id),
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE SET NULL
+);
+-- +goose Down
+DROP TABLE IF EXISTS server_bans;
+ALTER TABLE user_servers DROP COLUMN IF EXISTS muted_until;
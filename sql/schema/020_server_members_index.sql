@@ -0,0 +1,6 @@
+-- +goose Up
+-- Member lists are read per server, which the (user_id, server_id) primary
+-- key cannot serve.
+CREATE INDEX idx_user_servers_server_id ON user_servers(server_id);
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_servers_server_id;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Discovery searches name and description with this exact expression, so
+-- keep the two in sync.
+CREATE INDEX idx_servers_search ON servers USING GIN (
+    to_tsvector(
+        'simple',
+        server_name || ' ' || COALESCE(description, '')
+    )
+);
+CREATE TABLE server_tags (
+    server_id UUID NOT NULL,
+    tag TEXT NOT NULL CHECK (tag ~ '^[a-z0-9-]{1,32}$'),
+    PRIMARY KEY (server_id, tag),
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_server_tags_tag ON server_tags(tag);
+-- Activity sorting reads each server's newest message.
+CREATE INDEX idx_text_messages_channel_created ON text_messages(channel_id, created_at);
+-- +goose Down
+DROP INDEX IF EXISTS idx_text_messages_channel_created;
+DROP TABLE IF EXISTS server_tags;
+DROP INDEX IF EXISTS idx_servers_search;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE servers
+ADD COLUMN boost_count INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE server_boosts (
+    server_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (server_id, user_id),
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_server_boosts_user_id ON server_boosts(user_id);
+-- +goose Down
+DROP TABLE IF EXISTS server_boosts;
+ALTER TABLE servers DROP COLUMN IF EXISTS boost_count;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- member_count is kept by a trigger on user_servers, so every way a
+-- membership row appears or disappears (joins, leaves, kicks, bans and
+-- deleted accounts) moves the count in the same transaction.
+ALTER TABLE servers
+ALTER COLUMN member_count SET DEFAULT 0;
+UPDATE servers
+SET member_count = (
+        SELECT COUNT(*)
+        FROM user_servers
+        WHERE user_servers.server_id = servers.id
+    );
+-- +goose StatementBegin
+CREATE FUNCTION count_server_members() RETURNS TRIGGER AS $$ BEGIN IF TG_OP = 'INSERT' THEN
+UPDATE servers
+SET member_count = COALESCE(member_count, 0) + 1
+WHERE id = NEW.server_id;
+RETURN NEW;
+END IF;
+UPDATE servers
+SET member_count = GREATEST(COALESCE(member_count, 0) - 1, 0)
+WHERE id = OLD.server_id;
+RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+CREATE TRIGGER user_servers_member_count
+AFTER
+INSERT
+    OR DELETE ON user_servers FOR EACH ROW EXECUTE FUNCTION count_server_members();
+-- +goose Down
+DROP TRIGGER IF EXISTS user_servers_member_count ON user_servers;
+DROP FUNCTION IF EXISTS count_server_members();
+ALTER TABLE servers
+ALTER COLUMN member_count SET DEFAULT 1;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    server_id UUID NOT NULL,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id UUID,
+    before JSONB NOT NULL DEFAULT '{}',
+    after JSONB NOT NULL DEFAULT '{}',
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (server_id) REFERENCES servers(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_audit_log_server_created ON audit_log(server_id, created_at DESC, id DESC);
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY,
+    channel_id UUID NOT NULL,
+    creator_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    avatar_url TEXT,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (channel_id) REFERENCES text_channels(id) ON DELETE CASCADE,
+    FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_webhooks_channel_id ON webhooks(channel_id);
+-- Webhook messages are owned by the webhook's creator. The name and avatar
+-- they were posted under are kept on the message so they survive the
+-- webhook being renamed or deleted.
+ALTER TABLE text_messages
+ADD COLUMN webhook_id UUID REFERENCES webhooks(id) ON DELETE SET NULL,
+    ADD COLUMN author_name TEXT,
+    ADD COLUMN author_avatar TEXT;
+-- +goose Down
+ALTER TABLE text_messages DROP COLUMN IF EXISTS author_avatar,
+    DROP COLUMN IF EXISTS author_name,
+    DROP COLUMN IF EXISTS webhook_id;
+DROP TABLE IF EXISTS webhooks;
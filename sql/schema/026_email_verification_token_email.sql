@@ -0,0 +1,13 @@
+-- +goose Up
+-- A verification token confirms the address it was mailed to, not whatever
+-- the account's email happens to be when it is redeemed.
+ALTER TABLE email_verification_tokens
+ADD COLUMN email TEXT;
+UPDATE email_verification_tokens
+SET email = users.email
+FROM users
+WHERE users.id = email_verification_tokens.user_id;
+ALTER TABLE email_verification_tokens
+ALTER COLUMN email SET NOT NULL;
+-- +goose Down
+ALTER TABLE email_verification_tokens DROP COLUMN IF EXISTS email;
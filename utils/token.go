@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"strings"
@@ -25,23 +28,63 @@ var (
 	ErrEmptyToken    = &TokenError{Message: "Token is empty", Code: http.StatusUnauthorized}
 )
 
-func CreateToken(id uuid.UUID, jwtSecret string, expiresInSeconds int) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Issuer:    "gleamspeak",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Duration(expiresInSeconds) * time.Second)),
-		Subject:   id.String(),
+// Claims are the claims carried by every token issued by the API. SessionID
+// ties access tokens to a login session so they stop working once it is revoked.
+// TokenType keeps a refresh token from being presented as an access token.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+	TokenType string `json:"typ,omitempty"`
+}
+
+// Token types carried in the typ claim.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenKeys signs and verifies API tokens. It is implemented by
+// jwtkeys.KeySet.
+type TokenKeys interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	ValidMethods() []string
+}
+
+func CreateToken(id, sessionID uuid.UUID, keys TokenKeys, expiresInSeconds int) (string, error) {
+	claims := newClaims(id, sessionID, TokenTypeAccess, expiresInSeconds)
+	return signToken(claims, keys)
+}
+
+// CreateRefreshToken issues a token carrying tokenID as its jti so the
+// server-side refresh token record can be looked up and revoked.
+func CreateRefreshToken(id, sessionID, tokenID uuid.UUID, keys TokenKeys, expiresInSeconds int) (string, error) {
+	claims := newClaims(id, sessionID, TokenTypeRefresh, expiresInSeconds)
+	claims.ID = tokenID.String()
+	return signToken(claims, keys)
+}
+
+func newClaims(id, sessionID uuid.UUID, tokenType string, expiresInSeconds int) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "gleamspeak",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Duration(expiresInSeconds) * time.Second)),
+			Subject:   id.String(),
+		},
+		SessionID: sessionID.String(),
+		TokenType: tokenType,
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(jwtSecret))
+func signToken(claims jwt.Claims, keys TokenKeys) (string, error) {
+	signedToken, err := keys.Sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to create token: %w", err)
 	}
 	return signedToken, nil
 }
 
-
 func ExtractToken(r *http.Request, prefix string) (string, error) {
 	authHeader := r.Header.Get("Authorization")
 
@@ -72,14 +115,19 @@ func SetTokenCookie(w http.ResponseWriter, name, value string, maxAgeSeconds int
 	})
 }
 
-func ValidateToken(tokenString, jwtSecret string) (*jwt.Token, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(jwtSecret), nil
+func ClearTokenCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	})
+}
+
+func ValidateToken(tokenString string, keys TokenKeys) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
 
 	if err != nil {
 		return nil, err
@@ -87,3 +135,69 @@ func ValidateToken(tokenString, jwtSecret string) (*jwt.Token, error) {
 
 	return token, nil
 }
+
+func GenerateRandomToken(numBytes int) (string, error) {
+	b := make([]byte, numBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 digest used to store opaque tokens so a
+// database leak does not expose usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Opaque API tokens carry a prefix so they can be told apart from JWT access
+// tokens in an Authorization header. Bot tokens are personal access tokens
+// owned by a bot account.
+const (
+	PersonalAccessTokenPrefix = "gsp_"
+	BotTokenPrefix            = "gsb_"
+)
+
+func GeneratePersonalAccessToken() (string, error) {
+	return generatePrefixedToken(PersonalAccessTokenPrefix)
+}
+
+func GenerateBotToken() (string, error) {
+	return generatePrefixedToken(BotTokenPrefix)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix) || strings.HasPrefix(token, BotTokenPrefix)
+}
+
+func generatePrefixedToken(prefix string) (string, error) {
+	token, err := GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return prefix + token, nil
+}
+
+const inviteCodeAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateInviteCode returns a short code for sharing in links. Characters
+// that are easy to confuse when typed by hand are left out.
+func GenerateInviteCode(length int) (string, error) {
+	// Bytes at or above limit are discarded so every character is equally
+	// likely.
+	limit := 256 - 256%len(inviteCodeAlphabet)
+	code := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate invite code: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < length {
+				code = append(code, inviteCodeAlphabet[int(b)%len(inviteCodeAlphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}